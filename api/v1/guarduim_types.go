@@ -1,19 +1,370 @@
 package v1
 
 import (
+	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const (
+	// UnblockAnnotation asks the controller to unblock the user and to count
+	// failures from now on. Its value is the reason for the unblock.
+	UnblockAnnotation = "guard.guarduim.com/unblock"
+	// UnblockedByAnnotation is set by the admission webhook to the user that
+	// set UnblockAnnotation.
+	UnblockedByAnnotation = "guard.guarduim.com/unblocked-by"
+
+	// Finalizer lets the controller restore the user's access before a
+	// Guarduim is deleted.
+	Finalizer = "guard.guarduim.com/finalizer"
+)
+
+// Condition types set on Guarduim objects.
+const (
+	// ConditionReady is true when the last check of the user completed.
+	ConditionReady = "Ready"
+	// ConditionBlocked is true while the user is blocked.
+	ConditionBlocked = "Blocked"
+	// ConditionLogSourceAvailable is true when the log source was read.
+	ConditionLogSourceAvailable = "LogSourceAvailable"
+	// ConditionEnforcementApplied is true when the user's access matches
+	// the Blocked condition.
+	ConditionEnforcementApplied = "EnforcementApplied"
+	// ConditionWouldBlock is true in DryRun mode when the user would be
+	// blocked.
+	ConditionWouldBlock = "WouldBlock"
+	// ConditionSuspiciousLogin is true when the user logged in after
+	// SuccessAfterFailures failed logins within the window, a sign of a
+	// guessed or leaked password.
+	ConditionSuspiciousLogin = "SuspiciousLogin"
+	// ConditionLocationAnomaly is true when the user tried to log in from an
+	// unusual location within the window.
+	ConditionLocationAnomaly = "LocationAnomaly"
+	// ConditionRuleMatched is true when a custom detection rule matches the
+	// login attempts of the user within the window.
+	ConditionRuleMatched = "RuleMatched"
+)
+
+// EnforcementMode selects whether the controller blocks users.
+// +kubebuilder:validation:Enum=Enforce;DryRun;Disabled
+type EnforcementMode string
+
+const (
+	// EnforcementModeEnforce blocks users exceeding the threshold.
+	EnforcementModeEnforce EnforcementMode = "Enforce"
+	// EnforcementModeDryRun only reports the users that would be blocked
+	// in the WouldBlock condition and Events.
+	EnforcementModeDryRun EnforcementMode = "DryRun"
+	// EnforcementModeDisabled only counts failures.
+	EnforcementModeDisabled EnforcementMode = "Disabled"
+)
+
+// LocationDetection reports logins from unusual locations, looked up in the
+// GeoIP database of the manager.
+type LocationDetection struct {
+	// MaxSpeed is the speed in km/h above which travelling between the
+	// locations of two logins is impossible.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=1000
+	// +optional
+	MaxSpeed int `json:"maxSpeed,omitempty"`
+
+	// Block blocks the user after a login from an unusual location, as when
+	// the threshold is exceeded.
+	// +optional
+	Block bool `json:"block,omitempty"`
+}
+
+// LocationAnomalyType is what makes the location of a login unusual.
+// +kubebuilder:validation:Enum=NewCountry;NewASN;ImpossibleTravel
+type LocationAnomalyType string
+
+const (
+	// LocationNewCountry is a login from a country the user never logged
+	// in from.
+	LocationNewCountry LocationAnomalyType = "NewCountry"
+	// LocationNewASN is a login from an autonomous system the user never
+	// logged in from.
+	LocationNewASN LocationAnomalyType = "NewASN"
+	// LocationImpossibleTravel is a login too far from the previous one to
+	// travel between them in time.
+	LocationImpossibleTravel LocationAnomalyType = "ImpossibleTravel"
+)
+
+// DetectionRule is a CEL expression evaluated against the login attempts of
+// a user within the window. Users matching a rule with failed logins in the
+// window are blocked as when the threshold is exceeded. The expression must
+// evaluate to a bool and can use:
+//
+//   - username (string) and now (timestamp, the end of the window)
+//   - failures and logins (int), failed and successful logins
+//   - failures_1m, failures_5m, failures_10m and failures_1h (int), failed
+//     logins in the last minute, 5 minutes, 10 minutes and hour, also
+//     beyond a shorter window, but not before the end of the last block
+//   - distinct_ips and distinct_user_agents (int), client addresses and user
+//     agents of the failed logins
+//   - events, every login attempt, oldest first, with the timestamp,
+//     source_ip, user_agent, identity_provider and allowed keys
+//
+// e.g. "failures_10m > 5 && distinct_ips > 2".
+type DetectionRule struct {
+	// Name identifies the rule in status, conditions and Events.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
+	Name string `json:"name"`
+
+	// +kubebuilder:validation:MinLength=1
+	Expression string `json:"expression"`
+}
+
+// RuleMatches counts how often a detection rule started matching a user.
+type RuleMatches struct {
+	Name string `json:"name"`
+	// Matches is the number of times the rule started matching.
+	Matches int `json:"matches"`
+	// LastMatch is when the rule last started matching.
+	// +optional
+	LastMatch *metav1.Time `json:"lastMatch,omitempty"`
+}
+
 // GuarduimSpec defines the desired state of Guarduim
 type GuarduimSpec struct {
-	Username  string `json:"username"`
-	Threshold int    `json:"threshold"`
+	// Username is the user to watch. It can not be changed.
+	// +kubebuilder:validation:MinLength=1
+	Username string `json:"username"`
+	// +kubebuilder:validation:Minimum=0
+	Threshold int `json:"threshold"`
+
+	// LogSource selects where authentication events are read from, e.g.
+	// "oauth-server". Defaults to the log source configured on the manager.
+	// +optional
+	LogSource string `json:"logSource,omitempty"`
+
+	// Window limits counting to failures within this duration of the current
+	// time, e.g. "15m". A zero window counts every failure in the log.
+	// +kubebuilder:default="15m"
+	// +optional
+	Window *metav1.Duration `json:"window,omitempty"`
+
+	// BlockDuration is how long a user stays blocked once the threshold is
+	// exceeded. It doubles with every further block of the same user, up to
+	// MaxBlockDuration. A zero duration blocks the user for as long as the
+	// failures inside the window exceed the threshold.
+	// +kubebuilder:default="30m"
+	// +optional
+	BlockDuration *metav1.Duration `json:"blockDuration,omitempty"`
+
+	// MaxBlockDuration caps BlockDuration for repeat offenders. A zero
+	// duration lets the block duration grow without bound.
+	// +kubebuilder:default="24h"
+	// +optional
+	MaxBlockDuration *metav1.Duration `json:"maxBlockDuration,omitempty"`
+
+	// RevokeAuthorizeTokens also revokes the user's OAuth authorize tokens
+	// while blocked, besides the OAuth access tokens.
+	// +optional
+	RevokeAuthorizeTokens bool `json:"revokeAuthorizeTokens,omitempty"`
+
+	// EnforcementMode selects whether the user is blocked. In DryRun and
+	// Disabled modes no new block is started and a blocked user is unblocked
+	// at once. Defaults to the enforcement mode configured on the manager.
+	// +optional
+	EnforcementMode EnforcementMode `json:"enforcementMode,omitempty"`
+
+	// SuccessAfterFailures reports a successful login following at least this
+	// many consecutive failed logins as suspicious. Zero disables the check.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	SuccessAfterFailures int `json:"successAfterFailures,omitempty"`
+
+	// LocationDetection reports logins from countries or autonomous systems
+	// the user never logged in from, or too far from the previous login.
+	// Requires a GeoIP database configured on the manager.
+	// +optional
+	LocationDetection *LocationDetection `json:"locationDetection,omitempty"`
+
+	// Rules are custom detection rules blocking the user when any of them
+	// matches, besides the threshold.
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MaxItems=20
+	// +optional
+	Rules []DetectionRule `json:"rules,omitempty"`
 }
 
 // GuarduimStatus defines the observed state of Guarduim
 type GuarduimStatus struct {
+	// Conditions describe the last check of the user.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+
+	// ObservedGeneration is the generation of the spec the status reflects.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// LastCheckedTime is when the user was last checked.
+	// +optional
+	LastCheckedTime *metav1.Time `json:"lastCheckedTime,omitempty"`
+
 	FailureCount int  `json:"failureCount"`
 	Blocked      bool `json:"blocked"`
+
+	// BlockedAt is when the current or last block started.
+	// +optional
+	BlockedAt *metav1.Time `json:"blockedAt,omitempty"`
+	// BlockedUntil is when the current or last block ends, or when the user
+	// was last unblocked manually. Failures up to that time are not counted
+	// again once the block ended. It is unset for blocks without a duration.
+	// +optional
+	BlockedUntil *metav1.Time `json:"blockedUntil,omitempty"`
+	// BlockCount is how many times the user has been blocked.
+	// +optional
+	BlockCount int `json:"blockCount,omitempty"`
+
+	// LastUnblock records the last manual unblock.
+	// +optional
+	LastUnblock *Unblock `json:"lastUnblock,omitempty"`
+
+	// WindowStart and WindowEnd bound the failures counted in FailureCount.
+	// WindowStart is unset when the window is zero.
+	// +optional
+	WindowStart *metav1.Time `json:"windowStart,omitempty"`
+	// +optional
+	WindowEnd *metav1.Time `json:"windowEnd,omitempty"`
+
+	// Failures holds the times of the failures counted in FailureCount,
+	// oldest first. At most the 1000 most recent failures are kept.
+	// +optional
+	Failures []metav1.MicroTime `json:"failures,omitempty"`
+
+	// FirstFailure and LastFailure are the earliest and the most recent
+	// failed logins of the user seen by the controller.
+	// +optional
+	FirstFailure *metav1.Time `json:"firstFailure,omitempty"`
+	// +optional
+	LastFailure *metav1.Time `json:"lastFailure,omitempty"`
+
+	// SourceIPs are the client addresses of the failures counted in
+	// FailureCount, most recent first. At most 20 addresses are kept.
+	// +optional
+	SourceIPs []SourceIP `json:"sourceIPs,omitempty"`
+
+	// SuspiciousLogin is the last successful login that followed
+	// SuccessAfterFailures failed logins.
+	// +optional
+	SuspiciousLogin *SuspiciousLogin `json:"suspiciousLogin,omitempty"`
+
+	// KnownCountries and KnownASNs are where the user logged in from
+	// successfully, learned by LocationDetection. At most 50 of each are kept.
+	// +optional
+	KnownCountries []string `json:"knownCountries,omitempty"`
+	// +optional
+	KnownASNs []int64 `json:"knownASNs,omitempty"`
+	// LastLocation is where the last successful login checked by
+	// LocationDetection came from.
+	// +optional
+	LastLocation *LoginLocation `json:"lastLocation,omitempty"`
+	// LocationsCheckedUntil is the time of the last login, failed or
+	// successful, checked by LocationDetection.
+	// +optional
+	LocationsCheckedUntil *metav1.MicroTime `json:"locationsCheckedUntil,omitempty"`
+	// LocationAnomalies are the last logins from unusual locations, most
+	// recent first. At most 20 are kept.
+	// +optional
+	LocationAnomalies []LocationAnomaly `json:"locationAnomalies,omitempty"`
+
+	// MatchedRules are the names of the rules matching the login attempts
+	// within the window at the last check.
+	// +optional
+	MatchedRules []string `json:"matchedRules,omitempty"`
+	// RuleMatches counts how often each rule of the spec started matching
+	// the login attempts of the user.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	RuleMatches []RuleMatches `json:"ruleMatches,omitempty"`
+
+	// LogSource is the log source Failures were read from.
+	// +optional
+	LogSource string `json:"logSource,omitempty"`
+
+	// RevokedUsername is the user RevokedGroups were revoked from. The
+	// groups are given back to this user, whatever spec.username is then.
+	// +optional
+	RevokedUsername string `json:"revokedUsername,omitempty"`
+	// RevokedBindings lists the role bindings the user was removed from while
+	// blocked. They are restored when the user is unblocked, unless they were
+	// deleted in the meantime.
+	// +optional
+	RevokedBindings []RevokedBinding `json:"revokedBindings,omitempty"`
+	// RevokedGroups lists the OpenShift groups the user was removed from while
+	// blocked. They are restored when the user is unblocked.
+	// +optional
+	RevokedGroups []string `json:"revokedGroups,omitempty"`
+	// RevokedTokens counts the OAuth tokens of the user deleted since the
+	// user was blocked.
+	// +optional
+	RevokedTokens int `json:"revokedTokens,omitempty"`
+}
+
+// SourceIP counts the failed logins from a client address.
+type SourceIP struct {
+	Address      string `json:"address"`
+	FailureCount int    `json:"failureCount"`
+}
+
+// SuspiciousLogin is a successful login following failed logins.
+type SuspiciousLogin struct {
+	Time metav1.MicroTime `json:"time"`
+	// FailureCount is the number of consecutive failed logins before it.
+	FailureCount int `json:"failureCount"`
+	// +optional
+	SourceIP string `json:"sourceIP,omitempty"`
+}
+
+// LoginLocation is where a login came from.
+type LoginLocation struct {
+	Time     metav1.MicroTime `json:"time"`
+	SourceIP string           `json:"sourceIP"`
+	// Country is the ISO 3166-1 code of the country.
+	// +optional
+	Country string `json:"country,omitempty"`
+	// ASN is the autonomous system number.
+	// +optional
+	ASN int64 `json:"asn,omitempty"`
+}
+
+// LocationAnomaly is a login from an unusual location.
+type LocationAnomaly struct {
+	Type          LocationAnomalyType `json:"type"`
+	LoginLocation `json:",inline"`
+	// Succeeded is set when the login was allowed.
+	// +optional
+	Succeeded bool `json:"succeeded,omitempty"`
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// Unblock is a manual unblock requested with UnblockAnnotation.
+type Unblock struct {
+	Time metav1.Time `json:"time"`
+	// By is the user that requested the unblock.
+	// +optional
+	By string `json:"by,omitempty"`
+	// +optional
+	Reason string `json:"reason,omitempty"`
+}
+
+// RevokedBinding is a subject removed from a RoleBinding or ClusterRoleBinding.
+type RevokedBinding struct {
+	// +kubebuilder:validation:Enum=RoleBinding;ClusterRoleBinding
+	Kind string `json:"kind"`
+	// Namespace of a RoleBinding, empty for a ClusterRoleBinding.
+	// +optional
+	Namespace string         `json:"namespace,omitempty"`
+	Name      string         `json:"name"`
+	RoleRef   rbacv1.RoleRef `json:"roleRef"`
+	Subject   rbacv1.Subject `json:"subject"`
 }
 
 //+kubebuilder:object:root=true
@@ -0,0 +1,51 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GuarduimExemptionSpec defines the users of a GuarduimExemption. A user
+// matching any entry of any list is exempted.
+type GuarduimExemptionSpec struct {
+	// Usernames are exempted users, e.g. "kube:admin".
+	// +optional
+	Usernames []string `json:"usernames,omitempty"`
+
+	// UsernamePatterns are regular expressions matched against the whole
+	// username, e.g. "system:serviceaccount:ci:.*".
+	// +optional
+	UsernamePatterns []string `json:"usernamePatterns,omitempty"`
+
+	// Groups are OpenShift groups whose members are exempted.
+	// +optional
+	Groups []string `json:"groups,omitempty"`
+
+	// Reason documents why the users are exempted.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:path=guarduimexemptions,scope=Cluster
+//+kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.spec.reason`
+
+// GuarduimExemption lists users whose failed logins are counted but who are
+// never blocked, such as break-glass and system accounts.
+type GuarduimExemption struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              GuarduimExemptionSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GuarduimExemptionList contains a list of GuarduimExemption
+type GuarduimExemptionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GuarduimExemption `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GuarduimExemption{}, &GuarduimExemptionList{})
+}
@@ -0,0 +1,82 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// IncidentType is the kind of attack a GuarduimIncident reports.
+// +kubebuilder:validation:Enum=PasswordSpray;CredentialStuffing
+type IncidentType string
+
+const (
+	// IncidentPasswordSpray is a source failing to log in as many usernames.
+	IncidentPasswordSpray IncidentType = "PasswordSpray"
+	// IncidentCredentialStuffing is a source failing to log in as many
+	// usernames that also logged in as some of them, as leaked credentials do.
+	IncidentCredentialStuffing IncidentType = "CredentialStuffing"
+)
+
+// GuarduimIncidentSpec identifies the attack of a GuarduimIncident
+type GuarduimIncidentSpec struct {
+	Type IncidentType `json:"type"`
+
+	// SourceIP is the client address of the failed logins.
+	SourceIP string `json:"sourceIP"`
+	// UserAgent is the user agent of the failed logins, when the detection
+	// tells user agents apart.
+	// +optional
+	UserAgent string `json:"userAgent,omitempty"`
+}
+
+// GuarduimIncidentStatus defines the observed state of GuarduimIncident
+type GuarduimIncidentStatus struct {
+	// FirstSeen and LastSeen bound the failed logins seen from the source.
+	// +optional
+	FirstSeen metav1.MicroTime `json:"firstSeen,omitempty"`
+	// +optional
+	LastSeen metav1.MicroTime `json:"lastSeen,omitempty"`
+
+	// FailureCount is the number of failed logins seen from the source.
+	FailureCount int `json:"failureCount"`
+	// UsernameCount is the largest number of distinct usernames the source
+	// tried within the window of the policy.
+	UsernameCount int `json:"usernameCount"`
+	// Usernames are the accounts tried, sorted. At most the first 100 are
+	// kept.
+	// +optional
+	Usernames []string `json:"usernames,omitempty"`
+	// LoggedInUsernames are the accounts the source logged in as, sorted. At
+	// most the first 100 are kept.
+	// +optional
+	LoggedInUsernames []string `json:"loggedInUsernames,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:path=guarduimincidents,scope=Cluster
+//+kubebuilder:printcolumn:name="Type",type=string,JSONPath=`.spec.type`
+//+kubebuilder:printcolumn:name="Source",type=string,JSONPath=`.spec.sourceIP`
+//+kubebuilder:printcolumn:name="Users",type=integer,JSONPath=`.status.usernameCount`
+//+kubebuilder:printcolumn:name="Last Seen",type=date,JSONPath=`.status.lastSeen`
+
+// GuarduimIncident reports an attack spanning many users, raised by the
+// GuarduimPolicy that detected it.
+type GuarduimIncident struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              GuarduimIncidentSpec   `json:"spec,omitempty"`
+	Status            GuarduimIncidentStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GuarduimIncidentList contains a list of GuarduimIncident
+type GuarduimIncidentList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GuarduimIncident `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GuarduimIncident{}, &GuarduimIncidentList{})
+}
@@ -0,0 +1,235 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PolicyLabel is set on the objects generated for a GuarduimPolicy to the UID
+// of the policy, as policy names can be longer than label values.
+const PolicyLabel = "guard.guarduim.com/policy"
+
+// PolicyAction is what a GuarduimPolicy does when a user exceeds its threshold.
+// +kubebuilder:validation:Enum=Block;Alert
+type PolicyAction string
+
+const (
+	// PolicyActionBlock blocks the user.
+	PolicyActionBlock PolicyAction = "Block"
+	// PolicyActionAlert only reports the user in conditions and Events, the
+	// generated Guarduim objects use the DryRun enforcement mode.
+	PolicyActionAlert PolicyAction = "Alert"
+)
+
+// UserSelector selects users by name, group or identity provider. A user must
+// match every non-empty list, and any entry within a list.
+type UserSelector struct {
+	// UsernamePatterns are regular expressions matched against the whole
+	// username, e.g. "dev-.*".
+	// +optional
+	UsernamePatterns []string `json:"usernamePatterns,omitempty"`
+
+	// Groups are OpenShift groups the user must be a member of.
+	// +optional
+	Groups []string `json:"groups,omitempty"`
+
+	// IdentityProviders are the names of the OAuth identity providers the
+	// login attempts must go through, as in the /login/<name> path.
+	// +optional
+	IdentityProviders []string `json:"identityProviders,omitempty"`
+}
+
+// SourceIPBlocking blocks the client address ranges with too many failed
+// logins, whatever usernames they tried, with a NetworkPolicy.
+type SourceIPBlocking struct {
+	// Threshold is the number of failed logins from an address range within
+	// the policy window above which the range is blocked.
+	// +kubebuilder:validation:Minimum=0
+	Threshold int `json:"threshold"`
+
+	// IPv4PrefixLength groups IPv4 client addresses into ranges.
+	// +kubebuilder:validation:Minimum=8
+	// +kubebuilder:validation:Maximum=32
+	// +kubebuilder:default=32
+	// +optional
+	IPv4PrefixLength int `json:"ipv4PrefixLength,omitempty"`
+
+	// IPv6PrefixLength groups IPv6 client addresses into ranges.
+	// +kubebuilder:validation:Minimum=16
+	// +kubebuilder:validation:Maximum=128
+	// +kubebuilder:default=64
+	// +optional
+	IPv6PrefixLength int `json:"ipv6PrefixLength,omitempty"`
+
+	// Namespace and PodSelector select the pods the blocked ranges are
+	// denied access to. The pods must see the client address, e.g. an
+	// ingress controller behind a load balancer that preserves it.
+	// +kubebuilder:validation:MinLength=1
+	Namespace   string               `json:"namespace"`
+	PodSelector metav1.LabelSelector `json:"podSelector"`
+}
+
+// SprayDetection raises a GuarduimIncident for sources trying many usernames,
+// such as password spraying or credential stuffing, which no single user
+// threshold catches.
+type SprayDetection struct {
+	// UsernameThreshold is the number of distinct usernames a source may
+	// fail to log in as within the policy window.
+	// +kubebuilder:validation:Minimum=1
+	UsernameThreshold int `json:"usernameThreshold"`
+
+	// ByUserAgent tells apart the user agents of a client address, so that
+	// users behind a shared proxy are not taken for a single source.
+	// +optional
+	ByUserAgent bool `json:"byUserAgent,omitempty"`
+}
+
+// GuarduimPolicySpec defines the desired state of GuarduimPolicy
+type GuarduimPolicySpec struct {
+	// Selector limits the policy to some users. An empty selector applies
+	// the policy to every user seen in the log source.
+	// +optional
+	Selector UserSelector `json:"selector,omitempty"`
+
+	// +kubebuilder:validation:Minimum=0
+	Threshold int `json:"threshold"`
+
+	// Action is taken on users exceeding the threshold.
+	// +kubebuilder:default=Block
+	// +optional
+	Action PolicyAction `json:"action,omitempty"`
+
+	// LogSource selects where authentication events are read from. Defaults
+	// to the log source configured on the manager.
+	// +optional
+	LogSource string `json:"logSource,omitempty"`
+
+	// Window limits counting to failures within this duration of the
+	// current time.
+	// +kubebuilder:default="15m"
+	// +optional
+	Window *metav1.Duration `json:"window,omitempty"`
+
+	// BlockDuration is how long a user stays blocked, doubled for every
+	// further block up to MaxBlockDuration.
+	// +kubebuilder:default="30m"
+	// +optional
+	BlockDuration *metav1.Duration `json:"blockDuration,omitempty"`
+
+	// +kubebuilder:default="24h"
+	// +optional
+	MaxBlockDuration *metav1.Duration `json:"maxBlockDuration,omitempty"`
+
+	// RevokeAuthorizeTokens also revokes the OAuth authorize tokens of
+	// blocked users.
+	// +optional
+	RevokeAuthorizeTokens bool `json:"revokeAuthorizeTokens,omitempty"`
+
+	// SuccessAfterFailures reports a successful login of a user following at
+	// least this many consecutive failed logins as suspicious.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	SuccessAfterFailures int `json:"successAfterFailures,omitempty"`
+
+	// LocationDetection reports logins of users from unusual locations.
+	// +optional
+	LocationDetection *LocationDetection `json:"locationDetection,omitempty"`
+
+	// SourceIPBlocking also blocks the client address ranges with too many
+	// failed logins across all users.
+	// +optional
+	SourceIPBlocking *SourceIPBlocking `json:"sourceIPBlocking,omitempty"`
+
+	// SprayDetection raises incidents for sources trying many usernames.
+	// Sources that also logged in as some of them are reported as
+	// credential stuffing.
+	// +optional
+	SprayDetection *SprayDetection `json:"sprayDetection,omitempty"`
+
+	// Rules are custom detection rules applied to every matching user seen
+	// in the log source, besides the threshold. Users matching a rule are
+	// tracked even without failed logins, and blocked once they also fail
+	// to log in.
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MaxItems=20
+	// +optional
+	Rules []DetectionRule `json:"rules,omitempty"`
+
+	// MaxTrackedUsers bounds the number of Guarduim objects generated for
+	// the policy. Further users are left out, those with the fewest failed
+	// logins first, until tracked users are forgotten.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=1000
+	// +optional
+	MaxTrackedUsers int `json:"maxTrackedUsers,omitempty"`
+}
+
+// GuarduimPolicyStatus defines the observed state of GuarduimPolicy
+type GuarduimPolicyStatus struct {
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// TrackedUsers is the number of users with a Guarduim generated by the
+	// policy.
+	TrackedUsers int `json:"trackedUsers"`
+	// UntrackedUsers is the number of matching users left out by
+	// MaxTrackedUsers.
+	// +optional
+	UntrackedUsers int `json:"untrackedUsers,omitempty"`
+	// BlockedUsers is the number of those users currently blocked.
+	BlockedUsers int `json:"blockedUsers"`
+
+	// BlockedSourceRanges are the client address ranges currently blocked,
+	// in CIDR notation.
+	// +optional
+	BlockedSourceRanges []string `json:"blockedSourceRanges,omitempty"`
+
+	// Rules counts the users matching each rule of the spec.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Rules []RuleStatus `json:"rules,omitempty"`
+}
+
+// RuleStatus counts the users matching a detection rule.
+type RuleStatus struct {
+	Name string `json:"name"`
+	// MatchingUsers is the number of users matching the rule at the last
+	// reconcile.
+	MatchingUsers int `json:"matchingUsers"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:path=guarduimpolicies,scope=Cluster
+//+kubebuilder:printcolumn:name="Threshold",type=integer,JSONPath=`.spec.threshold`
+//+kubebuilder:printcolumn:name="Action",type=string,JSONPath=`.spec.action`
+//+kubebuilder:printcolumn:name="Tracked",type=integer,JSONPath=`.status.trackedUsers`
+//+kubebuilder:printcolumn:name="Blocked",type=integer,JSONPath=`.status.blockedUsers`
+
+// GuarduimPolicy applies a threshold to every matching user seen in the log
+// source, tracking each of them in a generated Guarduim.
+type GuarduimPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              GuarduimPolicySpec   `json:"spec,omitempty"`
+	Status            GuarduimPolicyStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GuarduimPolicyList contains a list of GuarduimPolicy
+type GuarduimPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GuarduimPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GuarduimPolicy{}, &GuarduimPolicyList{})
+}
@@ -0,0 +1,31 @@
+package v1
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ValidateUsername checks name against the rules the OpenShift API server applies
+// to User names, which are also valid Kubernetes usernames. It returns a list of
+// problems, or nil if the name is valid.
+func ValidateUsername(name string) []string {
+	if name == "" {
+		return []string{"must not be empty"}
+	}
+
+	var msgs []string
+	switch name {
+	case ".", "..", "~":
+		msgs = append(msgs, fmt.Sprintf("may not be %q", name))
+	}
+	for _, illegal := range []string{"/", "%"} {
+		if strings.Contains(name, illegal) {
+			msgs = append(msgs, fmt.Sprintf("may not contain %q", illegal))
+		}
+	}
+	if strings.IndexFunc(name, func(r rune) bool { return unicode.IsSpace(r) || !unicode.IsPrint(r) }) >= 0 {
+		msgs = append(msgs, "may not contain whitespace or control characters")
+	}
+	return msgs
+}
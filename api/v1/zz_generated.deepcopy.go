@@ -21,16 +21,32 @@ limitations under the License.
 package v1
 
 import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DetectionRule) DeepCopyInto(out *DetectionRule) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DetectionRule.
+func (in *DetectionRule) DeepCopy() *DetectionRule {
+	if in == nil {
+		return nil
+	}
+	out := new(DetectionRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Guarduim) DeepCopyInto(out *Guarduim) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Guarduim.
@@ -51,6 +67,195 @@ func (in *Guarduim) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GuarduimExemption) DeepCopyInto(out *GuarduimExemption) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GuarduimExemption.
+func (in *GuarduimExemption) DeepCopy() *GuarduimExemption {
+	if in == nil {
+		return nil
+	}
+	out := new(GuarduimExemption)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GuarduimExemption) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GuarduimExemptionList) DeepCopyInto(out *GuarduimExemptionList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GuarduimExemption, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GuarduimExemptionList.
+func (in *GuarduimExemptionList) DeepCopy() *GuarduimExemptionList {
+	if in == nil {
+		return nil
+	}
+	out := new(GuarduimExemptionList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GuarduimExemptionList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GuarduimExemptionSpec) DeepCopyInto(out *GuarduimExemptionSpec) {
+	*out = *in
+	if in.Usernames != nil {
+		in, out := &in.Usernames, &out.Usernames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.UsernamePatterns != nil {
+		in, out := &in.UsernamePatterns, &out.UsernamePatterns
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Groups != nil {
+		in, out := &in.Groups, &out.Groups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GuarduimExemptionSpec.
+func (in *GuarduimExemptionSpec) DeepCopy() *GuarduimExemptionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GuarduimExemptionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GuarduimIncident) DeepCopyInto(out *GuarduimIncident) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GuarduimIncident.
+func (in *GuarduimIncident) DeepCopy() *GuarduimIncident {
+	if in == nil {
+		return nil
+	}
+	out := new(GuarduimIncident)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GuarduimIncident) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GuarduimIncidentList) DeepCopyInto(out *GuarduimIncidentList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GuarduimIncident, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GuarduimIncidentList.
+func (in *GuarduimIncidentList) DeepCopy() *GuarduimIncidentList {
+	if in == nil {
+		return nil
+	}
+	out := new(GuarduimIncidentList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GuarduimIncidentList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GuarduimIncidentSpec) DeepCopyInto(out *GuarduimIncidentSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GuarduimIncidentSpec.
+func (in *GuarduimIncidentSpec) DeepCopy() *GuarduimIncidentSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GuarduimIncidentSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GuarduimIncidentStatus) DeepCopyInto(out *GuarduimIncidentStatus) {
+	*out = *in
+	in.FirstSeen.DeepCopyInto(&out.FirstSeen)
+	in.LastSeen.DeepCopyInto(&out.LastSeen)
+	if in.Usernames != nil {
+		in, out := &in.Usernames, &out.Usernames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LoggedInUsernames != nil {
+		in, out := &in.LoggedInUsernames, &out.LoggedInUsernames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GuarduimIncidentStatus.
+func (in *GuarduimIncidentStatus) DeepCopy() *GuarduimIncidentStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GuarduimIncidentStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GuarduimList) DeepCopyInto(out *GuarduimList) {
 	*out = *in
@@ -83,9 +288,176 @@ func (in *GuarduimList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GuarduimPolicy) DeepCopyInto(out *GuarduimPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GuarduimPolicy.
+func (in *GuarduimPolicy) DeepCopy() *GuarduimPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(GuarduimPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GuarduimPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GuarduimPolicyList) DeepCopyInto(out *GuarduimPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GuarduimPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GuarduimPolicyList.
+func (in *GuarduimPolicyList) DeepCopy() *GuarduimPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(GuarduimPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GuarduimPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GuarduimPolicySpec) DeepCopyInto(out *GuarduimPolicySpec) {
+	*out = *in
+	in.Selector.DeepCopyInto(&out.Selector)
+	if in.Window != nil {
+		in, out := &in.Window, &out.Window
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.BlockDuration != nil {
+		in, out := &in.BlockDuration, &out.BlockDuration
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.MaxBlockDuration != nil {
+		in, out := &in.MaxBlockDuration, &out.MaxBlockDuration
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.LocationDetection != nil {
+		in, out := &in.LocationDetection, &out.LocationDetection
+		*out = new(LocationDetection)
+		**out = **in
+	}
+	if in.SourceIPBlocking != nil {
+		in, out := &in.SourceIPBlocking, &out.SourceIPBlocking
+		*out = new(SourceIPBlocking)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SprayDetection != nil {
+		in, out := &in.SprayDetection, &out.SprayDetection
+		*out = new(SprayDetection)
+		**out = **in
+	}
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]DetectionRule, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GuarduimPolicySpec.
+func (in *GuarduimPolicySpec) DeepCopy() *GuarduimPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GuarduimPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GuarduimPolicyStatus) DeepCopyInto(out *GuarduimPolicyStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.BlockedSourceRanges != nil {
+		in, out := &in.BlockedSourceRanges, &out.BlockedSourceRanges
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]RuleStatus, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GuarduimPolicyStatus.
+func (in *GuarduimPolicyStatus) DeepCopy() *GuarduimPolicyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GuarduimPolicyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GuarduimSpec) DeepCopyInto(out *GuarduimSpec) {
 	*out = *in
+	if in.Window != nil {
+		in, out := &in.Window, &out.Window
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.BlockDuration != nil {
+		in, out := &in.BlockDuration, &out.BlockDuration
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.MaxBlockDuration != nil {
+		in, out := &in.MaxBlockDuration, &out.MaxBlockDuration
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.LocationDetection != nil {
+		in, out := &in.LocationDetection, &out.LocationDetection
+		*out = new(LocationDetection)
+		**out = **in
+	}
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]DetectionRule, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GuarduimSpec.
@@ -101,6 +473,111 @@ func (in *GuarduimSpec) DeepCopy() *GuarduimSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GuarduimStatus) DeepCopyInto(out *GuarduimStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastCheckedTime != nil {
+		in, out := &in.LastCheckedTime, &out.LastCheckedTime
+		*out = (*in).DeepCopy()
+	}
+	if in.BlockedAt != nil {
+		in, out := &in.BlockedAt, &out.BlockedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.BlockedUntil != nil {
+		in, out := &in.BlockedUntil, &out.BlockedUntil
+		*out = (*in).DeepCopy()
+	}
+	if in.LastUnblock != nil {
+		in, out := &in.LastUnblock, &out.LastUnblock
+		*out = new(Unblock)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.WindowStart != nil {
+		in, out := &in.WindowStart, &out.WindowStart
+		*out = (*in).DeepCopy()
+	}
+	if in.WindowEnd != nil {
+		in, out := &in.WindowEnd, &out.WindowEnd
+		*out = (*in).DeepCopy()
+	}
+	if in.Failures != nil {
+		in, out := &in.Failures, &out.Failures
+		*out = make([]metav1.MicroTime, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.FirstFailure != nil {
+		in, out := &in.FirstFailure, &out.FirstFailure
+		*out = (*in).DeepCopy()
+	}
+	if in.LastFailure != nil {
+		in, out := &in.LastFailure, &out.LastFailure
+		*out = (*in).DeepCopy()
+	}
+	if in.SourceIPs != nil {
+		in, out := &in.SourceIPs, &out.SourceIPs
+		*out = make([]SourceIP, len(*in))
+		copy(*out, *in)
+	}
+	if in.SuspiciousLogin != nil {
+		in, out := &in.SuspiciousLogin, &out.SuspiciousLogin
+		*out = new(SuspiciousLogin)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.KnownCountries != nil {
+		in, out := &in.KnownCountries, &out.KnownCountries
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.KnownASNs != nil {
+		in, out := &in.KnownASNs, &out.KnownASNs
+		*out = make([]int64, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastLocation != nil {
+		in, out := &in.LastLocation, &out.LastLocation
+		*out = new(LoginLocation)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LocationsCheckedUntil != nil {
+		in, out := &in.LocationsCheckedUntil, &out.LocationsCheckedUntil
+		*out = (*in).DeepCopy()
+	}
+	if in.LocationAnomalies != nil {
+		in, out := &in.LocationAnomalies, &out.LocationAnomalies
+		*out = make([]LocationAnomaly, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.MatchedRules != nil {
+		in, out := &in.MatchedRules, &out.MatchedRules
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RuleMatches != nil {
+		in, out := &in.RuleMatches, &out.RuleMatches
+		*out = make([]RuleMatches, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.RevokedBindings != nil {
+		in, out := &in.RevokedBindings, &out.RevokedBindings
+		*out = make([]RevokedBinding, len(*in))
+		copy(*out, *in)
+	}
+	if in.RevokedGroups != nil {
+		in, out := &in.RevokedGroups, &out.RevokedGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GuarduimStatus.
@@ -112,3 +589,209 @@ func (in *GuarduimStatus) DeepCopy() *GuarduimStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LocationAnomaly) DeepCopyInto(out *LocationAnomaly) {
+	*out = *in
+	in.LoginLocation.DeepCopyInto(&out.LoginLocation)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LocationAnomaly.
+func (in *LocationAnomaly) DeepCopy() *LocationAnomaly {
+	if in == nil {
+		return nil
+	}
+	out := new(LocationAnomaly)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LocationDetection) DeepCopyInto(out *LocationDetection) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LocationDetection.
+func (in *LocationDetection) DeepCopy() *LocationDetection {
+	if in == nil {
+		return nil
+	}
+	out := new(LocationDetection)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LoginLocation) DeepCopyInto(out *LoginLocation) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoginLocation.
+func (in *LoginLocation) DeepCopy() *LoginLocation {
+	if in == nil {
+		return nil
+	}
+	out := new(LoginLocation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RevokedBinding) DeepCopyInto(out *RevokedBinding) {
+	*out = *in
+	out.RoleRef = in.RoleRef
+	out.Subject = in.Subject
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RevokedBinding.
+func (in *RevokedBinding) DeepCopy() *RevokedBinding {
+	if in == nil {
+		return nil
+	}
+	out := new(RevokedBinding)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RuleMatches) DeepCopyInto(out *RuleMatches) {
+	*out = *in
+	if in.LastMatch != nil {
+		in, out := &in.LastMatch, &out.LastMatch
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RuleMatches.
+func (in *RuleMatches) DeepCopy() *RuleMatches {
+	if in == nil {
+		return nil
+	}
+	out := new(RuleMatches)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RuleStatus) DeepCopyInto(out *RuleStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RuleStatus.
+func (in *RuleStatus) DeepCopy() *RuleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RuleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SourceIP) DeepCopyInto(out *SourceIP) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SourceIP.
+func (in *SourceIP) DeepCopy() *SourceIP {
+	if in == nil {
+		return nil
+	}
+	out := new(SourceIP)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SourceIPBlocking) DeepCopyInto(out *SourceIPBlocking) {
+	*out = *in
+	in.PodSelector.DeepCopyInto(&out.PodSelector)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SourceIPBlocking.
+func (in *SourceIPBlocking) DeepCopy() *SourceIPBlocking {
+	if in == nil {
+		return nil
+	}
+	out := new(SourceIPBlocking)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SprayDetection) DeepCopyInto(out *SprayDetection) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SprayDetection.
+func (in *SprayDetection) DeepCopy() *SprayDetection {
+	if in == nil {
+		return nil
+	}
+	out := new(SprayDetection)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SuspiciousLogin) DeepCopyInto(out *SuspiciousLogin) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SuspiciousLogin.
+func (in *SuspiciousLogin) DeepCopy() *SuspiciousLogin {
+	if in == nil {
+		return nil
+	}
+	out := new(SuspiciousLogin)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Unblock) DeepCopyInto(out *Unblock) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Unblock.
+func (in *Unblock) DeepCopy() *Unblock {
+	if in == nil {
+		return nil
+	}
+	out := new(Unblock)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UserSelector) DeepCopyInto(out *UserSelector) {
+	*out = *in
+	if in.UsernamePatterns != nil {
+		in, out := &in.UsernamePatterns, &out.UsernamePatterns
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Groups != nil {
+		in, out := &in.Groups, &out.Groups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IdentityProviders != nil {
+		in, out := &in.IdentityProviders, &out.IdentityProviders
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserSelector.
+func (in *UserSelector) DeepCopy() *UserSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(UserSelector)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -19,15 +19,21 @@ package main
 import (
 	"crypto/tls"
 	"flag"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/dynamic"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
@@ -38,7 +44,10 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	guardv1 "github.com/SaifRehman/guarduim/api/v1"
+	"github.com/SaifRehman/guarduim/internal/auditlog"
 	"github.com/SaifRehman/guarduim/internal/controller"
+	"github.com/SaifRehman/guarduim/internal/geoip"
+	webhookguardv1 "github.com/SaifRehman/guarduim/internal/webhook/v1"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -63,6 +72,12 @@ func main() {
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var logSource string
+	var stateNamespace string
+	var enforcementMode string
+	var geoIPDatabases string
+	var trustedProxies int
+	var scanInterval, failureRetention time.Duration
 	var tlsOpts []func(*tls.Config)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
@@ -81,6 +96,23 @@ func main() {
 	flag.StringVar(&metricsCertKey, "metrics-cert-key", "tls.key", "The name of the metrics server key file.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.StringVar(&logSource, "log-source", controller.OAuthServerLogSource,
+		"The authentication event source used by Guarduims that do not set spec.logSource.")
+	flag.DurationVar(&scanInterval, "scan-interval", 30*time.Second,
+		"How often the authentication event sources are read.")
+	flag.DurationVar(&failureRetention, "failure-retention", 24*time.Hour,
+		"How long failed and successful logins read from the log sources are kept in memory.")
+	flag.StringVar(&stateNamespace, "state-namespace", os.Getenv("POD_NAMESPACE"),
+		"The namespace holding the log source cursors and the Guarduim objects generated for GuarduimPolicies. "+
+			"If empty, cursors are not persisted across restarts and policies are not applied.")
+	flag.StringVar(&enforcementMode, "enforcement-mode", string(guardv1.EnforcementModeEnforce),
+		"Enforcement mode of Guarduim objects that do not set spec.enforcementMode: Enforce, DryRun or Disabled.")
+	flag.StringVar(&geoIPDatabases, "geoip-database", "",
+		"Comma-separated paths of MaxMind format (mmdb) GeoIP databases, e.g. a City and an ASN database, "+
+			"used by spec.locationDetection. If empty, logins are not located.")
+	flag.IntVar(&trustedProxies, "trusted-proxies", 1,
+		"The number of proxies in front of the OAuth server, such as the ingress router, trusted to append "+
+			"the client address to X-Forwarded-For. The client of a login is the rightmost address they did not add.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -202,13 +234,135 @@ func main() {
 		os.Exit(1)
 	}
 
+	auditLog, err := auditlog.NewNodeLogReader(mgr.GetConfig())
+	if err != nil {
+		setupLog.Error(err, "unable to create audit log reader")
+		os.Exit(1)
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		setupLog.Error(err, "unable to create dynamic client")
+		os.Exit(1)
+	}
+
+	logSources := map[string]controller.LogSource{
+		controller.OAuthServerLogSource: &controller.OAuthServerSource{
+			Reader:         auditLog,
+			TrustedProxies: trustedProxies,
+			Log:            ctrl.Log.WithName("logsource").WithName(controller.OAuthServerLogSource),
+		},
+	}
+	if _, ok := logSources[logSource]; !ok {
+		setupLog.Error(nil, "unknown log source", "log-source", logSource)
+		os.Exit(1)
+	}
+
+	if trustedProxies < 0 {
+		setupLog.Error(nil, "negative number of trusted proxies", "trusted-proxies", trustedProxies)
+		os.Exit(1)
+	}
+
+	switch guardv1.EnforcementMode(enforcementMode) {
+	case guardv1.EnforcementModeEnforce, guardv1.EnforcementModeDryRun, guardv1.EnforcementModeDisabled:
+	default:
+		setupLog.Error(nil, "unknown enforcement mode", "enforcement-mode", enforcementMode)
+		os.Exit(1)
+	}
+
+	scanners := map[string]*controller.Scanner{}
+	for name, source := range logSources {
+		scanner := &controller.Scanner{
+			Name:      name,
+			Source:    source,
+			Interval:  scanInterval,
+			Retention: failureRetention,
+			Client:    mgr.GetClient(),
+			APIReader: mgr.GetAPIReader(),
+			Log:       ctrl.Log.WithName("scanner").WithName(name),
+		}
+		if stateNamespace != "" {
+			scanner.CursorConfigMap = types.NamespacedName{Namespace: stateNamespace, Name: "guarduim-cursor-" + name}
+		}
+		if err := mgr.Add(scanner); err != nil {
+			setupLog.Error(err, "unable to add log scanner to manager", "log-source", name)
+			os.Exit(1)
+		}
+		scanners[name] = scanner
+	}
+
+	if err := mgr.Add(&controller.LegacyCleanup{
+		Client:    mgr.GetClient(),
+		APIReader: mgr.GetAPIReader(),
+		Log:       ctrl.Log.WithName("legacy-cleanup"),
+	}); err != nil {
+		setupLog.Error(err, "unable to add legacy cleanup to manager")
+		os.Exit(1)
+	}
+
+	// The controller must never block itself out of the cluster
+	var protectedUsernames []string
+	if namespace, serviceAccount := os.Getenv("POD_NAMESPACE"), os.Getenv("SERVICE_ACCOUNT_NAME"); namespace != "" && serviceAccount != "" {
+		protectedUsernames = append(protectedUsernames, "system:serviceaccount:"+namespace+":"+serviceAccount)
+	}
+
+	// Logins are located with the GeoIP databases mounted in the manager
+	var geoIP controller.GeoIP
+	if geoIPDatabases != "" {
+		db, err := geoip.Open(strings.Split(geoIPDatabases, ",")...)
+		if err != nil {
+			setupLog.Error(err, "unable to open GeoIP database", "paths", geoIPDatabases)
+			os.Exit(1)
+		}
+		geoIP = db
+	}
+
 	if err = (&controller.GuarduimReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(), // Ensure it is set
+		Client:                 mgr.GetClient(),
+		Scheme:                 mgr.GetScheme(), // Ensure it is set
+		Log:                    ctrl.Log.WithName("controllers").WithName("Guarduim"),
+		Scanners:               scanners,
+		DefaultLogSource:       logSource,
+		Dynamic:                dynamicClient,
+		Recorder:               mgr.GetEventRecorderFor("guarduim-controller"),
+		DefaultEnforcementMode: guardv1.EnforcementMode(enforcementMode),
+		ProtectedUsernames:     protectedUsernames,
+		GeoIP:                  geoIP,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Guarduim")
 		os.Exit(1)
 	}
+	if err = (&controller.GuarduimPolicyReconciler{
+		Client:           mgr.GetClient(),
+		Scheme:           mgr.GetScheme(),
+		Log:              ctrl.Log.WithName("controllers").WithName("GuarduimPolicy"),
+		Scanners:         scanners,
+		DefaultLogSource: logSource,
+		Namespace:        stateNamespace,
+		Recorder:         mgr.GetEventRecorderFor("guarduimpolicy-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "GuarduimPolicy")
+		os.Exit(1)
+	}
+	// nolint:goconst
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = webhookguardv1.SetupGuarduimWebhookWithManager(mgr, slices.Sorted(maps.Keys(logSources))); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Guarduim")
+			os.Exit(1)
+		}
+		if err = webhookguardv1.SetupGuarduimPolicyWebhookWithManager(mgr, slices.Sorted(maps.Keys(logSources))); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "GuarduimPolicy")
+			os.Exit(1)
+		}
+		if err = webhookguardv1.SetupGuarduimExemptionWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "GuarduimExemption")
+			os.Exit(1)
+		}
+		if err = webhookguardv1.SetupBlockedUserWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "BlockedUser")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if metricsCertWatcher != nil {
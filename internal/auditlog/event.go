@@ -0,0 +1,142 @@
+// Package auditlog decodes the audit events written by the OpenShift OAuth
+// server to oauth-server/audit.log on every control plane node.
+package auditlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Annotations set by the OAuth server on every login attempt.
+const (
+	DecisionAnnotation = "authentication.openshift.io/decision"
+	UsernameAnnotation = "authentication.openshift.io/username"
+)
+
+// Decision is the outcome of an authentication attempt.
+type Decision string
+
+const (
+	DecisionAllow Decision = "allow"
+	DecisionDeny  Decision = "deny"
+	DecisionError Decision = "error"
+)
+
+// ErrNotAuthEvent is returned by Parse for audit events that carry no
+// authentication decision, e.g. requests for the OAuth metadata endpoints.
+var ErrNotAuthEvent = errors.New("audit event carries no authentication decision")
+
+// Event is a single authentication attempt recorded by the OAuth server.
+type Event struct {
+	AuditID    string
+	Timestamp  time.Time
+	Username   string
+	Decision   Decision
+	SourceIPs  []string
+	UserAgent  string
+	RequestURI string
+
+	// TrustedProxies is the number of trailing SourceIPs that are proxies
+	// trusted to forward the address of their client, such as the ingress
+	// router. It is set by the reader of the log, not read from it.
+	TrustedProxies int
+}
+
+// Denied reports whether the attempt was rejected.
+func (e Event) Denied() bool {
+	return e.Decision == DecisionDeny
+}
+
+// Allowed reports whether the attempt succeeded.
+func (e Event) Allowed() bool {
+	return e.Decision == DecisionAllow
+}
+
+// ClientIP returns the address of the client that made the attempt, or "" if
+// there is none. The source addresses are those of the X-Forwarded-For header,
+// which the client can set to anything, followed by the address the request
+// came from. The client is the rightmost address not added by one of the
+// trusted proxies. If the proxies added all of them, the client is unknown
+// and "" is returned rather than the address of a proxy.
+func (e Event) ClientIP() string {
+	i := len(e.SourceIPs) - 1 - e.TrustedProxies
+	if i < 0 {
+		return ""
+	}
+	return e.SourceIPs[i]
+}
+
+// IdentityProvider returns the identity provider of a login through the OAuth
+// server login form, /login/<provider>, and "" for other requests such as
+// basic-auth token requests.
+func (e Event) IdentityProvider() string {
+	uri, err := url.ParseRequestURI(e.RequestURI)
+	if err != nil {
+		return ""
+	}
+	provider, ok := strings.CutPrefix(uri.Path, "/login/")
+	if !ok {
+		return ""
+	}
+	provider, _, _ = strings.Cut(provider, "/")
+	return provider
+}
+
+// rawEvent mirrors the subset of audit.k8s.io/v1 Event the OAuth server emits.
+type rawEvent struct {
+	AuditID                  string            `json:"auditID"`
+	RequestURI               string            `json:"requestURI"`
+	SourceIPs                []string          `json:"sourceIPs"`
+	UserAgent                string            `json:"userAgent"`
+	RequestReceivedTimestamp time.Time         `json:"requestReceivedTimestamp"`
+	StageTimestamp           time.Time         `json:"stageTimestamp"`
+	Annotations              map[string]string `json:"annotations"`
+}
+
+// Parse decodes a single audit log line. A leading node name, as prepended by
+// `oc adm node-logs` when reading from several nodes, is ignored.
+func Parse(line []byte) (Event, error) {
+	raw, err := decode(line)
+	if err != nil {
+		return Event{}, err
+	}
+	return raw.event()
+}
+
+// decode unmarshals a single audit log line.
+func decode(line []byte) (rawEvent, error) {
+	if i := bytes.IndexByte(line, '{'); i > 0 {
+		line = line[i:]
+	}
+
+	var raw rawEvent
+	err := json.Unmarshal(line, &raw)
+	return raw, err
+}
+
+// event converts raw into an Event, or returns ErrNotAuthEvent.
+func (raw rawEvent) event() (Event, error) {
+	decision, ok := raw.Annotations[DecisionAnnotation]
+	if !ok {
+		return Event{}, ErrNotAuthEvent
+	}
+
+	timestamp := raw.RequestReceivedTimestamp
+	if timestamp.IsZero() {
+		timestamp = raw.StageTimestamp
+	}
+
+	return Event{
+		AuditID:    raw.AuditID,
+		Timestamp:  timestamp,
+		Username:   raw.Annotations[UsernameAnnotation],
+		Decision:   Decision(decision),
+		SourceIPs:  raw.SourceIPs,
+		UserAgent:  raw.UserAgent,
+		RequestURI: raw.RequestURI,
+	}, nil
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auditlog
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const (
+	denyLine = `{"kind":"Event","apiVersion":"audit.k8s.io/v1","level":"Metadata","auditID":"6f0c1b5e",` +
+		`"stage":"ResponseComplete","requestURI":"/oauth/token","verb":"post",` +
+		`"user":{"username":"system:anonymous","groups":["system:unauthenticated"]},` +
+		`"sourceIPs":["10.0.0.15"],"userAgent":"oc/4.17.0","responseStatus":{"metadata":{},"code":401},` +
+		`"requestReceivedTimestamp":"2025-01-20T10:15:30.123456Z","stageTimestamp":"2025-01-20T10:15:30.223456Z",` +
+		`"annotations":{"authentication.openshift.io/decision":"deny","authentication.openshift.io/username":"alice"}}`
+	allowLine = `{"kind":"Event","apiVersion":"audit.k8s.io/v1","auditID":"8d2e4a71","requestURI":"/oauth/authorize",` +
+		`"sourceIPs":["10.0.0.16"],"requestReceivedTimestamp":"2025-01-20T10:16:00Z",` +
+		`"annotations":{"authentication.openshift.io/decision":"allow","authentication.openshift.io/username":"bob"}}`
+	metadataLine = `{"kind":"Event","apiVersion":"audit.k8s.io/v1","auditID":"0b9a","requestURI":"/healthz",` +
+		`"requestReceivedTimestamp":"2025-01-20T10:16:00Z","annotations":{}}`
+)
+
+var _ = Describe("Parse", func() {
+	It("decodes a denied login", func() {
+		event, err := Parse([]byte(denyLine))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(event).To(Equal(Event{
+			AuditID:    "6f0c1b5e",
+			Timestamp:  time.Date(2025, 1, 20, 10, 15, 30, 123456000, time.UTC),
+			Username:   "alice",
+			Decision:   DecisionDeny,
+			SourceIPs:  []string{"10.0.0.15"},
+			UserAgent:  "oc/4.17.0",
+			RequestURI: "/oauth/token",
+		}))
+		Expect(event.Denied()).To(BeTrue())
+	})
+
+	It("ignores a node name prefix", func() {
+		event, err := Parse([]byte("master-0 " + allowLine))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(event.Username).To(Equal("bob"))
+		Expect(event.Denied()).To(BeFalse())
+	})
+
+	It("rejects events without an authentication decision", func() {
+		_, err := Parse([]byte(metadataLine))
+		Expect(err).To(MatchError(ErrNotAuthEvent))
+	})
+})
+
+var _ = Describe("Event.IdentityProvider", func() {
+	It("returns the provider of a login form request", func() {
+		Expect(Event{RequestURI: "/login/htpasswd_provider?then=%2Foauth%2Fauthorize"}.IdentityProvider()).To(Equal("htpasswd_provider"))
+	})
+
+	It("returns no provider for other requests", func() {
+		Expect(Event{RequestURI: "/oauth/authorize?client_id=openshift-challenging-client"}.IdentityProvider()).To(BeEmpty())
+		Expect(Event{}.IdentityProvider()).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Event.ClientIP", func() {
+	It("returns the rightmost address not added by a trusted proxy", func() {
+		forwarded := Event{SourceIPs: []string{"192.0.2.1", "203.0.113.7", "10.128.0.5"}}
+		Expect(forwarded.ClientIP()).To(Equal("10.128.0.5"))
+		forwarded.TrustedProxies = 1
+		Expect(forwarded.ClientIP()).To(Equal("203.0.113.7"))
+		forwarded.TrustedProxies = 2
+		Expect(forwarded.ClientIP()).To(Equal("192.0.2.1"))
+		forwarded.TrustedProxies = 3
+		Expect(forwarded.ClientIP()).To(BeEmpty())
+		forwarded.TrustedProxies = 5
+		Expect(forwarded.ClientIP()).To(BeEmpty())
+	})
+
+	It("returns no address for events without one", func() {
+		Expect(Event{TrustedProxies: 1}.ClientIP()).To(BeEmpty())
+		Expect(Event{SourceIPs: []string{"10.128.0.5"}, TrustedProxies: 1}.ClientIP()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,111 @@
+package auditlog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	// MasterNodeSelector selects the control plane nodes running the OAuth server.
+	MasterNodeSelector = "node-role.kubernetes.io/master"
+
+	// OAuthServerAuditLogPath is the OAuth server audit log relative to the node log directory.
+	OAuthServerAuditLogPath = "oauth-server/audit.log"
+)
+
+// NodeLogReader fetches audit logs through the API server node proxy, the same
+// /api/v1/nodes/<node>/proxy/logs endpoint `oc adm node-logs` uses.
+type NodeLogReader struct {
+	Client       kubernetes.Interface
+	NodeSelector string
+	Path         string
+}
+
+// NewNodeLogReader returns a NodeLogReader for the OAuth server audit log on
+// the master nodes of the cluster cfg points at.
+func NewNodeLogReader(cfg *rest.Config) (*NodeLogReader, error) {
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &NodeLogReader{
+		Client:       clientset,
+		NodeSelector: MasterNodeSelector,
+		Path:         OAuthServerAuditLogPath,
+	}, nil
+}
+
+// Nodes returns the names of the nodes matching the node selector.
+func (r *NodeLogReader) Nodes(ctx context.Context) ([]string, error) {
+	nodes, err := r.Client.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: r.NodeSelector})
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(nodes.Items))
+	for _, node := range nodes.Items {
+		names = append(names, node.Name)
+	}
+	return names, nil
+}
+
+// ReadNode returns the authentication events recorded on node after pos,
+// together with the position to resume from. Only the bytes after pos are
+// requested; if the log was rotated since, the new log is read from the start.
+func (r *NodeLogReader) ReadNode(ctx context.Context, node string, pos Position) ([]Event, Position, error) {
+	if pos.AuditID != "" {
+		data, base, err := r.fetch(ctx, node, pos.Offset)
+		if err != nil {
+			return nil, pos, err
+		}
+		if data != nil {
+			if events, next, ok := ReadFrom(data, base, pos); ok {
+				return events, next, nil
+			}
+		}
+		pos = Position{Timestamp: pos.Timestamp}
+	}
+
+	data, base, err := r.fetch(ctx, node, 0)
+	if err != nil {
+		return nil, pos, err
+	}
+	events, next, _ := ReadFrom(data, base, pos)
+	return events, next, nil
+}
+
+// fetch returns the node log from offset onwards and the offset the returned
+// data actually starts at, which is 0 if the kubelet ignored the range. It
+// returns nil data if the log is now shorter than offset.
+func (r *NodeLogReader) fetch(ctx context.Context, node string, offset int64) ([]byte, int64, error) {
+	req := r.Client.CoreV1().RESTClient().Get().
+		AbsPath("/api/v1/nodes", node, "proxy", "logs", r.Path)
+	if offset > 0 {
+		req = req.SetHeader("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	result := req.Do(ctx)
+	data, err := result.Raw()
+	if err != nil {
+		var status apierrors.APIStatus
+		if errors.As(err, &status) && status.Status().Code == http.StatusRequestedRangeNotSatisfiable {
+			return nil, 0, nil
+		}
+		return nil, 0, fmt.Errorf("fetching %s from node %s: %w", r.Path, node, err)
+	}
+
+	var code int
+	result.StatusCode(&code)
+	if code != http.StatusPartialContent {
+		offset = 0
+	}
+	return data, offset, nil
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auditlog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+var _ = Describe("NodeLogReader", func() {
+	var (
+		server        *httptest.Server
+		reader        *NodeLogReader
+		labelSelector string
+		rangeHeader   string
+	)
+
+	BeforeEach(func() {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/api/v1/nodes", func(w http.ResponseWriter, r *http.Request) {
+			labelSelector = r.URL.Query().Get("labelSelector")
+			nodes := corev1.NodeList{
+				TypeMeta: metav1.TypeMeta{Kind: "NodeList", APIVersion: "v1"},
+				Items: []corev1.Node{
+					{ObjectMeta: metav1.ObjectMeta{Name: "master-0"}},
+					{ObjectMeta: metav1.ObjectMeta{Name: "master-1"}},
+				},
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(nodes)
+		})
+		mux.HandleFunc("/api/v1/nodes/master-0/proxy/logs/oauth-server/audit.log", func(w http.ResponseWriter, r *http.Request) {
+			rangeHeader = r.Header.Get("Range")
+			content := strings.NewReader(denyLine + "\n" + metadataLine + "\n")
+			http.ServeContent(w, r, "audit.log", time.Time{}, content)
+		})
+		mux.HandleFunc("/api/v1/nodes/master-1/proxy/logs/oauth-server/audit.log", func(w http.ResponseWriter, r *http.Request) {
+			http.ServeContent(w, r, "audit.log", time.Time{}, strings.NewReader(allowLine+"\n"))
+		})
+		server = httptest.NewServer(mux)
+
+		var err error
+		reader, err = NewNodeLogReader(&rest.Config{Host: server.URL})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("discovers master nodes by label", func() {
+		nodes, err := reader.Nodes(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nodes).To(Equal([]string{"master-0", "master-1"}))
+		Expect(labelSelector).To(Equal(MasterNodeSelector))
+	})
+
+	It("reads a node audit log through the node proxy", func() {
+		events, pos, err := reader.ReadNode(context.Background(), "master-0", Position{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(events).To(HaveLen(1))
+		Expect(events[0].Username).To(Equal("alice"))
+		Expect(pos.AuditID).To(Equal("0b9a"))
+		Expect(pos.Offset).To(Equal(int64(len(denyLine) + 1)))
+	})
+
+	It("only requests the bytes after the cursor", func() {
+		_, pos, err := reader.ReadNode(context.Background(), "master-0", Position{})
+		Expect(err).NotTo(HaveOccurred())
+
+		events, next, err := reader.ReadNode(context.Background(), "master-0", pos)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(events).To(BeEmpty())
+		Expect(next).To(Equal(pos))
+		Expect(rangeHeader).To(Equal(fmt.Sprintf("bytes=%d-", pos.Offset)))
+	})
+
+	It("rereads a rotated log from the start", func() {
+		events, _, err := reader.ReadNode(context.Background(), "master-1", Position{Offset: 4096, AuditID: "6f0c1b5e"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(events).To(HaveLen(1))
+		Expect(events[0].Username).To(Equal("bob"))
+	})
+
+	It("fails when a node log cannot be fetched", func() {
+		reader.Path = "kube-apiserver/audit.log"
+
+		_, _, err := reader.ReadNode(context.Background(), "master-0", Position{})
+		Expect(err).To(HaveOccurred())
+	})
+})
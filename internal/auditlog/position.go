@@ -0,0 +1,82 @@
+package auditlog
+
+import (
+	"bytes"
+	"time"
+)
+
+// Position identifies the last line read from an audit log, so that the next
+// read can resume after it.
+type Position struct {
+	// Offset is the byte offset at which the last read line starts.
+	Offset int64 `json:"offset"`
+	// AuditID is the audit ID of the last read line. It is checked on the
+	// next read to detect that the log was rotated.
+	AuditID string `json:"auditID,omitempty"`
+	// Timestamp is the time of the last authentication event read. After a
+	// rotation, events up to it are skipped.
+	Timestamp time.Time `json:"timestamp,omitempty"`
+}
+
+// ReadFrom decodes the complete lines of data, which holds an audit log from
+// byte offset base onwards, and returns the authentication events recorded
+// after pos together with the position of the last complete line. A trailing
+// line without a newline is still being written and is left for the next read.
+//
+// ok is false when pos names a line that is not present in data, which means
+// the log was rotated and should be read again from the start.
+func ReadFrom(data []byte, base int64, pos Position) (events []Event, next Position, ok bool) {
+	next = pos
+	resuming := pos.AuditID != ""
+	if resuming && base > pos.Offset {
+		return nil, pos, false
+	}
+
+	found := !resuming
+	offset := base
+	for {
+		end := bytes.IndexByte(data, '\n')
+		if end < 0 {
+			break
+		}
+		line := data[:end]
+		lineOffset := offset
+		data = data[end+1:]
+		offset += int64(end) + 1
+
+		if resuming && lineOffset < pos.Offset {
+			continue
+		}
+
+		raw, err := decode(line)
+		if resuming && lineOffset == pos.Offset {
+			if err != nil || raw.AuditID != pos.AuditID {
+				return nil, pos, false
+			}
+			found = true
+			continue
+		}
+		if !found {
+			return nil, pos, false
+		}
+		if err != nil || raw.AuditID == "" {
+			continue
+		}
+		next.Offset = lineOffset
+		next.AuditID = raw.AuditID
+
+		event, err := raw.event()
+		if err != nil {
+			continue
+		}
+		if !resuming && !pos.Timestamp.IsZero() && !event.Timestamp.After(pos.Timestamp) {
+			continue
+		}
+		events = append(events, event)
+		if event.Timestamp.After(next.Timestamp) {
+			next.Timestamp = event.Timestamp
+		}
+	}
+
+	return events, next, found
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auditlog
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ReadFrom", func() {
+	log := []byte(denyLine + "\n" + metadataLine + "\n" + allowLine + "\n")
+	metadataOffset := int64(len(denyLine) + 1)
+
+	It("reads every complete line from the start", func() {
+		events, next, ok := ReadFrom(log, 0, Position{})
+		Expect(ok).To(BeTrue())
+		Expect(events).To(HaveLen(2))
+		Expect(next.AuditID).To(Equal("8d2e4a71"))
+		Expect(next.Offset).To(Equal(metadataOffset + int64(len(metadataLine)+1)))
+		Expect(next.Timestamp).To(Equal(events[1].Timestamp))
+	})
+
+	It("leaves a line that is still being written for the next read", func() {
+		events, next, ok := ReadFrom(log[:len(log)-10], 0, Position{})
+		Expect(ok).To(BeTrue())
+		Expect(events).To(HaveLen(1))
+		Expect(next.AuditID).To(Equal("0b9a"))
+	})
+
+	It("resumes after the line named by the position", func() {
+		events, _, ok := ReadFrom(log[metadataOffset:], metadataOffset, Position{Offset: metadataOffset, AuditID: "0b9a"})
+		Expect(ok).To(BeTrue())
+		Expect(events).To(HaveLen(1))
+		Expect(events[0].Username).To(Equal("bob"))
+	})
+
+	It("resumes when the whole log was returned", func() {
+		events, _, ok := ReadFrom(log, 0, Position{Offset: metadataOffset, AuditID: "0b9a"})
+		Expect(ok).To(BeTrue())
+		Expect(events).To(HaveLen(1))
+	})
+
+	It("detects that the log was rotated", func() {
+		_, _, ok := ReadFrom(log, 0, Position{Offset: metadataOffset, AuditID: "replaced"})
+		Expect(ok).To(BeFalse())
+	})
+})
@@ -0,0 +1,174 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "github.com/SaifRehman/guarduim/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Condition reasons
+const (
+	reasonChecked               = "Checked"
+	reasonUnblockFailed         = "UnblockFailed"
+	reasonUnknownLogSource      = "UnknownLogSource"
+	reasonWaitingForLogSource   = "WaitingForLogSource"
+	reasonLogSourceFailed       = "LogSourceFailed"
+	reasonLogSourceRead         = "LogSourceRead"
+	reasonThresholdExceeded     = "ThresholdExceeded"
+	reasonBelowThreshold        = "BelowThreshold"
+	reasonDryRun                = "DryRun"
+	reasonEnforcementDisabled   = "EnforcementDisabled"
+	reasonExempt                = "Exempt"
+	reasonExemptionCheckFailed  = "ExemptionCheckFailed"
+	reasonAccessRevoked         = "AccessRevoked"
+	reasonAccessRestored        = "AccessRestored"
+	reasonRevokeFailed          = "RevokeFailed"
+	reasonTokenRevocationFailed = "TokenRevocationFailed"
+	reasonRestoreFailed         = "RestoreFailed"
+	reasonLoginAfterFailures    = "LoginAfterFailures"
+	reasonNoSuspiciousLogin     = "NoSuspiciousLogin"
+	reasonUnusualLocation       = "UnusualLocation"
+	reasonUsualLocations        = "UsualLocations"
+	reasonNoGeoIPDatabase       = "NoGeoIPDatabase"
+	reasonRuleMatched           = "RuleMatched"
+	reasonNoRuleMatched         = "NoRuleMatched"
+	reasonInvalidRule           = "InvalidRule"
+)
+
+// setCondition sets a condition of the Guarduim for its current generation
+func setCondition(guarduim *v1.Guarduim, conditionType string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&guarduim.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		ObservedGeneration: guarduim.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// recordFailure sets conditionType and Ready to false because of err, emits a
+// Warning event and saves the status, so that the failure shows on the Guarduim
+func (r *GuarduimReconciler) recordFailure(ctx context.Context, guarduim *v1.Guarduim, conditionType, reason string, err error) {
+	r.Recorder.Event(guarduim, corev1.EventTypeWarning, reason, err.Error())
+	setCondition(guarduim, conditionType, metav1.ConditionFalse, reason, err.Error())
+	setCondition(guarduim, v1.ConditionReady, metav1.ConditionFalse, reason, err.Error())
+	if err := r.Client.Status().Update(ctx, guarduim); err != nil {
+		r.Log.Error(err, "Failed to update Guarduim status", "guarduim", guarduim.Name)
+	}
+}
+
+// setCheckedConditions sets the conditions of a check that completed
+func setCheckedConditions(guarduim *v1.Guarduim, sourceName string) {
+	status := guarduim.Status
+	setCondition(guarduim, v1.ConditionLogSourceAvailable, metav1.ConditionTrue, reasonLogSourceRead,
+		fmt.Sprintf("Read log source %q", sourceName))
+
+	if status.Blocked {
+		reason, message := reasonThresholdExceeded, fmt.Sprintf("Failed logins exceeded the threshold of %d", guarduim.Spec.Threshold)
+		switch {
+		case status.FailureCount > guarduim.Spec.Threshold:
+		case len(status.MatchedRules) > 0:
+			reason, message = reasonRuleMatched, fmt.Sprintf("Login attempts match rule %q", status.MatchedRules[0])
+		case len(status.LocationAnomalies) > 0:
+			reason, message = reasonUnusualLocation, "Login from an unusual location, "+status.LocationAnomalies[0].Message
+		}
+		if status.BlockedUntil != nil {
+			message += fmt.Sprintf(", blocked until %s", status.BlockedUntil.UTC().Format(time.RFC3339))
+		}
+		setCondition(guarduim, v1.ConditionBlocked, metav1.ConditionTrue, reason, message)
+		setCondition(guarduim, v1.ConditionEnforcementApplied, metav1.ConditionTrue, reasonAccessRevoked,
+			fmt.Sprintf("Removed from %d role bindings and %d groups, revoked %d OAuth tokens",
+				len(status.RevokedBindings), len(status.RevokedGroups), status.RevokedTokens))
+	} else {
+		setCondition(guarduim, v1.ConditionBlocked, metav1.ConditionFalse, reasonBelowThreshold,
+			fmt.Sprintf("%d failed logins, threshold %d", status.FailureCount, guarduim.Spec.Threshold))
+		setCondition(guarduim, v1.ConditionEnforcementApplied, metav1.ConditionTrue, reasonAccessRestored,
+			"The user has all of its access")
+	}
+
+	setCondition(guarduim, v1.ConditionReady, metav1.ConditionTrue, reasonChecked, "The user was checked")
+}
+
+// setWouldBlockCondition sets the WouldBlock condition in DryRun mode and
+// removes it otherwise. unenforced is why a threshold exceeded did not block
+// the user, if it did not, and cause what would have blocked it.
+func setWouldBlockCondition(guarduim *v1.Guarduim, mode v1.EnforcementMode, unenforced, cause string) {
+	if mode != v1.EnforcementModeDryRun {
+		meta.RemoveStatusCondition(&guarduim.Status.Conditions, v1.ConditionWouldBlock)
+		return
+	}
+
+	switch unenforced {
+	case reasonDryRun:
+		setCondition(guarduim, v1.ConditionWouldBlock, metav1.ConditionTrue, reasonThresholdExceeded,
+			"Would be blocked after "+cause)
+	case reasonExempt:
+		setCondition(guarduim, v1.ConditionWouldBlock, metav1.ConditionFalse, reasonExempt, "The user is exempted")
+	default:
+		setCondition(guarduim, v1.ConditionWouldBlock, metav1.ConditionFalse, reasonBelowThreshold,
+			fmt.Sprintf("%d failed logins, threshold %d", guarduim.Status.FailureCount, guarduim.Spec.Threshold))
+	}
+}
+
+// setSuspiciousLoginCondition sets the SuspiciousLogin condition from the
+// last suspicious login, true while it is later than windowStart, and removes
+// it when the check is disabled
+func setSuspiciousLoginCondition(guarduim *v1.Guarduim, windowStart time.Time) {
+	threshold := guarduim.Spec.SuccessAfterFailures
+	if threshold == 0 {
+		meta.RemoveStatusCondition(&guarduim.Status.Conditions, v1.ConditionSuspiciousLogin)
+		return
+	}
+
+	if login := guarduim.Status.SuspiciousLogin; login != nil && login.Time.After(windowStart) {
+		setCondition(guarduim, v1.ConditionSuspiciousLogin, metav1.ConditionTrue, reasonLoginAfterFailures,
+			fmt.Sprintf("Logged in at %s after %d failed logins", login.Time.UTC().Format(time.RFC3339), login.FailureCount))
+	} else {
+		setCondition(guarduim, v1.ConditionSuspiciousLogin, metav1.ConditionFalse, reasonNoSuspiciousLogin,
+			fmt.Sprintf("No login after %d or more failed logins", threshold))
+	}
+}
+
+// setLocationAnomalyCondition sets the LocationAnomaly condition from the last
+// location anomaly, true while it is later than windowStart, and removes it
+// when the detection is disabled. available tells whether the manager has a
+// GeoIP database.
+func setLocationAnomalyCondition(guarduim *v1.Guarduim, windowStart time.Time, available bool) {
+	switch anomaly := recentLocationAnomaly(&guarduim.Status, windowStart); {
+	case guarduim.Spec.LocationDetection == nil:
+		meta.RemoveStatusCondition(&guarduim.Status.Conditions, v1.ConditionLocationAnomaly)
+	case !available:
+		setCondition(guarduim, v1.ConditionLocationAnomaly, metav1.ConditionUnknown, reasonNoGeoIPDatabase,
+			"No GeoIP database is configured on the manager")
+	case anomaly != nil:
+		setCondition(guarduim, v1.ConditionLocationAnomaly, metav1.ConditionTrue, string(anomaly.Type),
+			fmt.Sprintf("Login from %s at %s, %s", anomaly.SourceIP, anomaly.Time.UTC().Format(time.RFC3339), anomaly.Message))
+	default:
+		setCondition(guarduim, v1.ConditionLocationAnomaly, metav1.ConditionFalse, reasonUsualLocations,
+			"No login from an unusual location")
+	}
+}
+
+// setRuleMatchedCondition sets the RuleMatched condition from the rules
+// matched at the last check, unknown when some rules failed with err, and
+// removes it when there are no rules
+func setRuleMatchedCondition(guarduim *v1.Guarduim, err error) {
+	switch matched := guarduim.Status.MatchedRules; {
+	case len(guarduim.Spec.Rules) == 0:
+		meta.RemoveStatusCondition(&guarduim.Status.Conditions, v1.ConditionRuleMatched)
+	case len(matched) > 0:
+		setCondition(guarduim, v1.ConditionRuleMatched, metav1.ConditionTrue, reasonRuleMatched,
+			fmt.Sprintf("Login attempts match rules %s", strings.Join(matched, ", ")))
+	case err != nil:
+		setCondition(guarduim, v1.ConditionRuleMatched, metav1.ConditionUnknown, reasonInvalidRule, err.Error())
+	default:
+		setCondition(guarduim, v1.ConditionRuleMatched, metav1.ConditionFalse, reasonNoRuleMatched,
+			fmt.Sprintf("No login attempts match the %d rules", len(guarduim.Spec.Rules)))
+	}
+}
@@ -0,0 +1,274 @@
+package controller
+
+import (
+	"context"
+	"slices"
+
+	v1 "github.com/SaifRehman/guarduim/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Kinds of the bindings recorded in RevokedBinding
+const (
+	roleBindingKind        = "RoleBinding"
+	clusterRoleBindingKind = "ClusterRoleBinding"
+)
+
+// groupGVK is the OpenShift user group, absent on plain Kubernetes clusters
+var groupGVK = schema.GroupVersionKind{Group: "user.openshift.io", Version: "v1", Kind: "Group"}
+
+// revokeAccess removes the user from every role binding and OpenShift group it
+// belongs to. The memberships are recorded in the Guarduim status before they
+// are removed, so that restoreAccess can put them back.
+func (r *GuarduimReconciler) revokeAccess(ctx context.Context, guarduim *v1.Guarduim) error {
+	username := guarduim.Spec.Username
+
+	bindings, err := r.userBindings(ctx, username)
+	if err != nil {
+		return err
+	}
+	groups, err := r.userGroups(ctx, username)
+	if err != nil {
+		return err
+	}
+	if len(bindings) == 0 && len(groups) == 0 {
+		return nil
+	}
+
+	// Record the memberships first, a failure below must not lose them
+	guarduim.Status.RevokedUsername = username
+	for _, binding := range bindings {
+		if !slices.Contains(guarduim.Status.RevokedBindings, binding) {
+			guarduim.Status.RevokedBindings = append(guarduim.Status.RevokedBindings, binding)
+		}
+	}
+	for _, group := range groups {
+		if !slices.Contains(guarduim.Status.RevokedGroups, group) {
+			guarduim.Status.RevokedGroups = append(guarduim.Status.RevokedGroups, group)
+		}
+	}
+	if err := r.Client.Status().Update(ctx, guarduim); err != nil {
+		return err
+	}
+
+	for _, binding := range bindings {
+		if err := r.removeSubject(ctx, binding); err != nil {
+			return err
+		}
+	}
+	for _, group := range groups {
+		if err := r.updateGroupUsers(ctx, group, func(users []string) []string {
+			return slices.DeleteFunc(users, func(user string) bool { return user == username })
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreAccess puts back the memberships recorded by revokeAccess and clears
+// them from the status. Bindings deleted while the user was blocked are not
+// recreated, an Event reports them instead.
+func (r *GuarduimReconciler) restoreAccess(ctx context.Context, guarduim *v1.Guarduim) error {
+	// Groups go back to the user they were revoked from
+	username := guarduim.Status.RevokedUsername
+	if username == "" {
+		username = guarduim.Spec.Username
+	}
+
+	for _, binding := range guarduim.Status.RevokedBindings {
+		restored, err := r.restoreSubject(ctx, binding)
+		if err != nil {
+			return err
+		}
+		if !restored {
+			r.recordUserEvent(ctx, guarduim, corev1.EventTypeWarning, eventBindingNotRestored,
+				"Not restoring %s %s of user %q, it was deleted or its role changed while the user was blocked",
+				binding.Kind, client.ObjectKey{Namespace: binding.Namespace, Name: binding.Name}, binding.Subject.Name)
+		}
+	}
+	for _, group := range guarduim.Status.RevokedGroups {
+		if err := r.updateGroupUsers(ctx, group, func(users []string) []string {
+			if slices.Contains(users, username) {
+				return users
+			}
+			return append(users, username)
+		}); err != nil {
+			return err
+		}
+	}
+
+	guarduim.Status.RevokedUsername = ""
+	guarduim.Status.RevokedBindings = nil
+	guarduim.Status.RevokedGroups = nil
+	return nil
+}
+
+// userBindings returns the RoleBindings and ClusterRoleBindings with username as a subject
+func (r *GuarduimReconciler) userBindings(ctx context.Context, username string) ([]v1.RevokedBinding, error) {
+	var bindings []v1.RevokedBinding
+
+	clusterRoleBindings := &rbacv1.ClusterRoleBindingList{}
+	if err := r.Client.List(ctx, clusterRoleBindings); err != nil {
+		return nil, err
+	}
+	for _, binding := range clusterRoleBindings.Items {
+		for _, subject := range binding.Subjects {
+			if isUserSubject(subject, username) {
+				bindings = append(bindings, v1.RevokedBinding{
+					Kind:    clusterRoleBindingKind,
+					Name:    binding.Name,
+					RoleRef: binding.RoleRef,
+					Subject: subject,
+				})
+			}
+		}
+	}
+
+	roleBindings := &rbacv1.RoleBindingList{}
+	if err := r.Client.List(ctx, roleBindings); err != nil {
+		return nil, err
+	}
+	for _, binding := range roleBindings.Items {
+		for _, subject := range binding.Subjects {
+			if isUserSubject(subject, username) {
+				bindings = append(bindings, v1.RevokedBinding{
+					Kind:      roleBindingKind,
+					Namespace: binding.Namespace,
+					Name:      binding.Name,
+					RoleRef:   binding.RoleRef,
+					Subject:   subject,
+				})
+			}
+		}
+	}
+
+	return bindings, nil
+}
+
+// isUserSubject reports whether subject is the user with the given name
+func isUserSubject(subject rbacv1.Subject, username string) bool {
+	return subject.Kind == rbacv1.UserKind && subject.Name == username
+}
+
+// getBinding fetches the binding recorded in revoked and returns it together
+// with its subjects and role reference
+func (r *GuarduimReconciler) getBinding(ctx context.Context, revoked v1.RevokedBinding) (client.Object, *[]rbacv1.Subject, *rbacv1.RoleRef, error) {
+	key := client.ObjectKey{Namespace: revoked.Namespace, Name: revoked.Name}
+	if revoked.Kind == clusterRoleBindingKind {
+		binding := &rbacv1.ClusterRoleBinding{}
+		err := r.Client.Get(ctx, key, binding)
+		return binding, &binding.Subjects, &binding.RoleRef, err
+	}
+
+	binding := &rbacv1.RoleBinding{}
+	err := r.Client.Get(ctx, key, binding)
+	return binding, &binding.Subjects, &binding.RoleRef, err
+}
+
+// removeSubject removes the recorded subject from its binding. The binding is
+// kept, even without subjects, so that it can be restored as it was.
+func (r *GuarduimReconciler) removeSubject(ctx context.Context, revoked v1.RevokedBinding) error {
+	binding, subjects, _, err := r.getBinding(ctx, revoked)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	if !slices.Contains(*subjects, revoked.Subject) {
+		return nil
+	}
+	*subjects = slices.DeleteFunc(*subjects, func(subject rbacv1.Subject) bool { return subject == revoked.Subject })
+	return r.Client.Update(ctx, binding)
+}
+
+// restoreSubject adds the recorded subject back to its binding and reports
+// whether it did. Bindings deleted while the user was blocked, or recreated
+// with another role, are left alone: restoring them would grant privileges
+// nobody asked for.
+func (r *GuarduimReconciler) restoreSubject(ctx context.Context, revoked v1.RevokedBinding) (bool, error) {
+	binding, subjects, roleRef, err := r.getBinding(ctx, revoked)
+	if errors.IsNotFound(err) {
+		r.Log.Info("Not restoring deleted binding", "kind", revoked.Kind,
+			"namespace", revoked.Namespace, "name", revoked.Name, "user", revoked.Subject.Name)
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if *roleRef != revoked.RoleRef {
+		r.Log.Info("Not restoring binding whose role changed", "kind", revoked.Kind,
+			"namespace", revoked.Namespace, "name", revoked.Name, "user", revoked.Subject.Name)
+		return false, nil
+	}
+	if slices.Contains(*subjects, revoked.Subject) {
+		return true, nil
+	}
+	*subjects = append(*subjects, revoked.Subject)
+	return true, r.Client.Update(ctx, binding)
+}
+
+// userGroups returns the names of the OpenShift groups username is a member
+// of. It returns no groups on clusters without the OpenShift user API.
+func (r *GuarduimReconciler) userGroups(ctx context.Context, username string) ([]string, error) {
+	members, err := groupsByUser(ctx, r.Client)
+	if err != nil {
+		return nil, err
+	}
+	return members[username], nil
+}
+
+// groupsByUser returns the names of the OpenShift groups of every user that
+// is a member of one. It returns no groups on clusters without the OpenShift
+// user API.
+func groupsByUser(ctx context.Context, reader client.Reader) (map[string][]string, error) {
+	groups := &unstructured.UnstructuredList{}
+	groups.SetGroupVersionKind(groupGVK.GroupVersion().WithKind(groupGVK.Kind + "List"))
+	if err := reader.List(ctx, groups); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	members := map[string][]string{}
+	for _, group := range groups.Items {
+		users, _, _ := unstructured.NestedStringSlice(group.Object, "users")
+		for _, user := range users {
+			members[user] = append(members[user], group.GetName())
+		}
+	}
+	return members, nil
+}
+
+// updateGroupUsers replaces the users of an OpenShift group with the result of
+// update. Groups that no longer exist are ignored.
+func (r *GuarduimReconciler) updateGroupUsers(ctx context.Context, name string, update func([]string) []string) error {
+	group := &unstructured.Unstructured{}
+	group.SetGroupVersionKind(groupGVK)
+	if err := r.Client.Get(ctx, client.ObjectKey{Name: name}, group); err != nil {
+		if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return nil
+		}
+		return err
+	}
+
+	users, _, _ := unstructured.NestedStringSlice(group.Object, "users")
+	updated := update(slices.Clone(users))
+	if slices.Equal(users, updated) {
+		return nil
+	}
+	if err := unstructured.SetNestedStringSlice(group.Object, updated, "users"); err != nil {
+		return err
+	}
+	return r.Client.Update(ctx, group)
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	guardv1 "github.com/SaifRehman/guarduim/api/v1"
+)
+
+var _ = Describe("Access revocation", func() {
+	ctx := context.Background()
+
+	bob := rbacv1.Subject{Kind: rbacv1.UserKind, APIGroup: rbacv1.GroupName, Name: "bob"}
+	carol := rbacv1.Subject{Kind: rbacv1.UserKind, APIGroup: rbacv1.GroupName, Name: "carol"}
+
+	var (
+		guarduim           *guardv1.Guarduim
+		clusterRoleBinding *rbacv1.ClusterRoleBinding
+		roleBinding        *rbacv1.RoleBinding
+		reconciler         *GuarduimReconciler
+	)
+
+	BeforeEach(func() {
+		guarduim = &guardv1.Guarduim{
+			ObjectMeta: metav1.ObjectMeta{Name: "bob", Namespace: "default"},
+			Spec:       guardv1.GuarduimSpec{Username: "bob", Threshold: 1},
+		}
+		Expect(k8sClient.Create(ctx, guarduim)).To(Succeed())
+
+		clusterRoleBinding = &rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "bob-view"},
+			Subjects:   []rbacv1.Subject{bob, carol},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "view"},
+		}
+		Expect(k8sClient.Create(ctx, clusterRoleBinding)).To(Succeed())
+
+		roleBinding = &rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "bob-edit", Namespace: "default"},
+			Subjects:   []rbacv1.Subject{bob},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "edit"},
+		}
+		Expect(k8sClient.Create(ctx, roleBinding)).To(Succeed())
+
+		reconciler, _ = newTestReconciler(nil)
+	})
+
+	AfterEach(func() {
+		Expect(deleteGuarduim(ctx, guarduim)).To(Succeed())
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, clusterRoleBinding))).To(Succeed())
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, roleBinding))).To(Succeed())
+	})
+
+	It("removes the user from its bindings and records them in status", func() {
+		Expect(reconciler.revokeAccess(ctx, guarduim)).To(Succeed())
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(clusterRoleBinding), clusterRoleBinding)).To(Succeed())
+		Expect(clusterRoleBinding.Subjects).To(Equal([]rbacv1.Subject{carol}))
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(roleBinding), roleBinding)).To(Succeed())
+		Expect(roleBinding.Subjects).To(BeEmpty())
+
+		stored := &guardv1.Guarduim{}
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(guarduim), stored)).To(Succeed())
+		Expect(stored.Status.RevokedBindings).To(ConsistOf(
+			guardv1.RevokedBinding{Kind: "ClusterRoleBinding", Name: "bob-view", RoleRef: clusterRoleBinding.RoleRef, Subject: bob},
+			guardv1.RevokedBinding{Kind: "RoleBinding", Namespace: "default", Name: "bob-edit", RoleRef: roleBinding.RoleRef, Subject: bob},
+		))
+	})
+
+	It("restores the bindings on unblock, but not deleted ones", func() {
+		Expect(reconciler.revokeAccess(ctx, guarduim)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, clusterRoleBinding)).To(Succeed())
+
+		Expect(reconciler.restoreAccess(ctx, guarduim)).To(Succeed())
+		Expect(guarduim.Status.RevokedBindings).To(BeEmpty())
+		Expect(guarduim.Status.RevokedUsername).To(BeEmpty())
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(clusterRoleBinding), &rbacv1.ClusterRoleBinding{})).
+			To(Satisfy(errors.IsNotFound))
+		Expect(reconciler.Recorder.(*record.FakeRecorder).Events).To(Receive(HavePrefix("Warning BindingNotRestored")))
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(roleBinding), roleBinding)).To(Succeed())
+		Expect(roleBinding.Subjects).To(Equal([]rbacv1.Subject{bob}))
+	})
+
+	It("records the user the access was revoked from", func() {
+		Expect(reconciler.revokeAccess(ctx, guarduim)).To(Succeed())
+		Expect(guarduim.Status.RevokedUsername).To(Equal("bob"))
+	})
+
+	It("restores the bindings and deletes the legacy binding when the Guarduim is deleted", func() {
+		legacyBinding := &rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "block-user-bob"},
+			Subjects:   []rbacv1.Subject{bob},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "blocked-user"},
+		}
+		Expect(k8sClient.Create(ctx, legacyBinding)).To(Succeed())
+
+		controllerutil.AddFinalizer(guarduim, guardv1.Finalizer)
+		Expect(k8sClient.Update(ctx, guarduim)).To(Succeed())
+		Expect(reconciler.revokeAccess(ctx, guarduim)).To(Succeed())
+		guarduim.Status.Blocked = true
+		Expect(k8sClient.Status().Update(ctx, guarduim)).To(Succeed())
+
+		reconciler.Recorder = record.NewFakeRecorder(10)
+		Expect(k8sClient.Delete(ctx, guarduim)).To(Succeed())
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(guarduim)})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(guarduim), &guardv1.Guarduim{})).To(Satisfy(errors.IsNotFound))
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(clusterRoleBinding), clusterRoleBinding)).To(Succeed())
+		Expect(clusterRoleBinding.Subjects).To(ConsistOf(bob, carol))
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(roleBinding), roleBinding)).To(Succeed())
+		Expect(roleBinding.Subjects).To(Equal([]rbacv1.Subject{bob}))
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(legacyBinding), legacyBinding)).To(Satisfy(errors.IsNotFound))
+	})
+})
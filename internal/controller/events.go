@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"context"
+
+	v1 "github.com/SaifRehman/guarduim/api/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Event reasons
+const (
+	eventBlocked       = "Blocked"
+	eventUnblocked     = "Unblocked"
+	eventTokensRevoked = "TokensRevoked"
+
+	eventBindingNotRestored = "BindingNotRestored"
+
+	eventThresholdExceeded = "ThresholdExceeded"
+	eventWouldBlock        = "WouldBlock"
+
+	eventSourceRangesBlocked = "SourceRangesBlocked"
+	eventPasswordSpray       = "PasswordSpray"
+	eventCredentialStuffing  = "CredentialStuffing"
+	eventSuspiciousLogin     = "SuspiciousLogin"
+	eventRuleMatched         = "RuleMatched"
+
+	// Logins from unusual locations use their v1.LocationAnomalyType
+)
+
+// userGVK is the OpenShift user, absent on plain Kubernetes clusters
+var userGVK = schema.GroupVersionKind{Group: "user.openshift.io", Version: "v1", Kind: "User"}
+
+// recordUserEvent emits an event on the Guarduim and on the OpenShift User it
+// watches, if there is one, so that it also shows when describing the user
+func (r *GuarduimReconciler) recordUserEvent(ctx context.Context, guarduim *v1.Guarduim, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.Recorder.Eventf(guarduim, eventtype, reason, messageFmt, args...)
+
+	user := &unstructured.Unstructured{}
+	user.SetGroupVersionKind(userGVK)
+	if err := r.Client.Get(ctx, client.ObjectKey{Name: guarduim.Spec.Username}, user); err != nil {
+		return
+	}
+	r.Recorder.Eventf(user, eventtype, reason, messageFmt, args...)
+}
@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+
+	v1 "github.com/SaifRehman/guarduim/api/v1"
+)
+
+//+kubebuilder:rbac:groups=guard.guarduim.com,resources=guarduimexemptions,verbs=get;list;watch
+
+// exemption returns what exempts the user of guarduim from being blocked, or
+// "" if nothing does. The ProtectedUsernames of the reconciler can not be
+// overridden, other users are exempted by GuarduimExemption objects. A blocked
+// user is still a member of the groups revoked from them.
+func (r *GuarduimReconciler) exemption(ctx context.Context, guarduim *v1.Guarduim) (string, error) {
+	username := guarduim.Spec.Username
+	if slices.Contains(r.ProtectedUsernames, username) {
+		return "the protection of the controller's own account", nil
+	}
+
+	exemptions := &v1.GuarduimExemptionList{}
+	if err := r.Client.List(ctx, exemptions); err != nil {
+		return "", err
+	}
+	var groups []string
+	groupsListed := false
+	for _, exemption := range exemptions.Items {
+		spec := exemption.Spec
+		matched := slices.Contains(spec.Usernames, username) ||
+			slices.ContainsFunc(spec.UsernamePatterns, func(pattern string) bool {
+				// Invalid patterns are rejected by the webhook, skip any that got through
+				re, err := regexp.Compile("^(?:" + pattern + ")$")
+				return err == nil && re.MatchString(username)
+			})
+		if !matched && len(spec.Groups) > 0 {
+			if !groupsListed {
+				var err error
+				if groups, err = r.userGroups(ctx, username); err != nil {
+					return "", err
+				}
+				if guarduim.Status.RevokedUsername == username {
+					groups = append(groups, guarduim.Status.RevokedGroups...)
+				}
+				groupsListed = true
+			}
+			matched = slices.ContainsFunc(groups, func(group string) bool { return slices.Contains(spec.Groups, group) })
+		}
+		if matched {
+			return fmt.Sprintf("GuarduimExemption %q", exemption.Name), nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	guardv1 "github.com/SaifRehman/guarduim/api/v1"
+	"github.com/SaifRehman/guarduim/internal/auditlog"
+)
+
+var _ = Describe("Exemptions", func() {
+	ctx := context.Background()
+	const controllerAccount = "system:serviceaccount:guarduim-system:guarduim-controller-manager"
+
+	var recorder *record.FakeRecorder
+
+	BeforeEach(func() {
+		exemption := &guardv1.GuarduimExemption{
+			ObjectMeta: metav1.ObjectMeta{Name: "ci"},
+			Spec: guardv1.GuarduimExemptionSpec{
+				Usernames:        []string{"kube:admin"},
+				UsernamePatterns: []string{"system:serviceaccount:ci:.*"},
+			},
+		}
+		Expect(k8sClient.Create(ctx, exemption)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ctx, exemption)
+	})
+
+	reconcileUser := func(username string, blocked bool) *guardv1.Guarduim {
+		key := types.NamespacedName{Name: "exempted", Namespace: "default"}
+		resource := &guardv1.Guarduim{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+			Spec:       guardv1.GuarduimSpec{Username: username, Threshold: 1},
+		}
+		Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		DeferCleanup(deleteGuarduim, ctx, resource)
+		if blocked {
+			resource.Status.Blocked = true
+			Expect(k8sClient.Status().Update(ctx, resource)).To(Succeed())
+		}
+
+		failedAt := time.Now().Add(-time.Minute)
+		var reconciler *GuarduimReconciler
+		reconciler, recorder = newTestReconciler(newTestScanner(ctx, staticLogSource{
+			{Username: username, Decision: auditlog.DecisionDeny, Timestamp: failedAt},
+			{Username: username, Decision: auditlog.DecisionDeny, Timestamp: failedAt},
+		}))
+		reconciler.ProtectedUsernames = []string{controllerAccount}
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		guarduim := &guardv1.Guarduim{}
+		Expect(k8sClient.Get(ctx, key, guarduim)).To(Succeed())
+		return guarduim
+	}
+
+	It("counts but never blocks the controller's own account", func() {
+		guarduim := reconcileUser(controllerAccount, false)
+		Expect(guarduim.Status.FailureCount).To(Equal(2))
+		Expect(guarduim.Status.Blocked).To(BeFalse())
+		condition := meta.FindStatusCondition(guarduim.Status.Conditions, guardv1.ConditionBlocked)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Reason).To(Equal(reasonExempt))
+		Expect(recorder.Events).To(Receive(HavePrefix("Warning ThresholdExceeded")))
+	})
+
+	It("unblocks a user once exempted", func() {
+		guarduim := reconcileUser("system:serviceaccount:ci:deployer", true)
+		Expect(guarduim.Status.Blocked).To(BeFalse())
+		Expect(meta.FindStatusCondition(guarduim.Status.Conditions, guardv1.ConditionBlocked).Reason).To(Equal(reasonExempt))
+		Expect(recorder.Events).To(Receive(ContainSubstring(`exempted by GuarduimExemption "ci"`)))
+	})
+
+	It("unblocks a member of an exempted group the block removed from it", func() {
+		exemption := &guardv1.GuarduimExemption{
+			ObjectMeta: metav1.ObjectMeta{Name: "break-glass"},
+			Spec:       guardv1.GuarduimExemptionSpec{Groups: []string{"break-glass"}},
+		}
+		Expect(k8sClient.Create(ctx, exemption)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ctx, exemption)
+
+		key := types.NamespacedName{Name: "exempted", Namespace: "default"}
+		resource := &guardv1.Guarduim{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+			Spec:       guardv1.GuarduimSpec{Username: "oscar", Threshold: 1},
+		}
+		Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		DeferCleanup(deleteGuarduim, ctx, resource)
+		resource.Status.Blocked = true
+		resource.Status.RevokedUsername = "oscar"
+		resource.Status.RevokedGroups = []string{"break-glass"}
+		Expect(k8sClient.Status().Update(ctx, resource)).To(Succeed())
+
+		var reconciler *GuarduimReconciler
+		failedAt := time.Now().Add(-time.Minute)
+		reconciler, recorder = newTestReconciler(newTestScanner(ctx, staticLogSource{
+			{Username: "oscar", Decision: auditlog.DecisionDeny, Timestamp: failedAt},
+			{Username: "oscar", Decision: auditlog.DecisionDeny, Timestamp: failedAt},
+		}))
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		guarduim := &guardv1.Guarduim{}
+		Expect(k8sClient.Get(ctx, key, guarduim)).To(Succeed())
+		Expect(guarduim.Status.Blocked).To(BeFalse())
+		Expect(meta.FindStatusCondition(guarduim.Status.Conditions, guardv1.ConditionBlocked).Reason).To(Equal(reasonExempt))
+		Expect(recorder.Events).To(Receive(ContainSubstring(`exempted by GuarduimExemption "break-glass"`)))
+	})
+
+	It("blocks users without an exemption", func() {
+		guarduim := reconcileUser("system:serviceaccount:cd:deployer", false)
+		Expect(guarduim.Status.Blocked).To(BeTrue())
+	})
+})
@@ -2,32 +2,74 @@ package controller
 
 import (
 	"context"
-	"os/exec"
-	"strconv"
-	"strings"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
 	"time"
 
 	v1 "github.com/SaifRehman/guarduim/api/v1"
+	"github.com/SaifRehman/guarduim/internal/auditlog"
+	"github.com/SaifRehman/guarduim/internal/rules"
 	"github.com/go-logr/logr"
-	rbacv1 "k8s.io/api/rbac/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
+// maxTrackedFailures bounds the failure times kept in GuarduimStatus.
+const maxTrackedFailures = 1000
+
+// maxSourceIPs bounds the client addresses kept in GuarduimStatus.
+const maxSourceIPs = 20
+
 // GuarduimReconciler reconciles a Guarduim object
 type GuarduimReconciler struct {
 	Client client.Client
 	Scheme *runtime.Scheme
 	Log    logr.Logger
+
+	// Scanners holds the shared scanner of every log source by name.
+	Scanners map[string]*Scanner
+	// DefaultLogSource is used for Guarduims that do not set spec.logSource.
+	DefaultLogSource string
+	// Dynamic revokes the OAuth tokens of blocked users.
+	Dynamic dynamic.Interface
+	// Recorder emits Events on Guarduim objects.
+	Recorder record.EventRecorder
+	// DefaultEnforcementMode is used for Guarduims that do not set
+	// spec.enforcementMode. Defaults to Enforce.
+	DefaultEnforcementMode v1.EnforcementMode
+	// ProtectedUsernames are never blocked, whatever the exemptions say,
+	// such as the controller's own service account.
+	ProtectedUsernames []string
+	// GeoIP looks up the locations of logins for spec.locationDetection.
+	// Nil disables the detection.
+	GeoIP GeoIP
 }
 
-//+kubebuilder:rbac:groups=guard.example.com,resources=guarduims,verbs=get;list;watch;create;update;patch;delete
-//+kubebuilder:rbac:groups=guard.example.com,resources=guarduims/status,verbs=get;update;patch
-//+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=clusterroles;clusterrolebindings,verbs=get;list;create;delete;update;patch
+//+kubebuilder:rbac:groups=guard.guarduim.com,resources=guarduims,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=guard.guarduim.com,resources=guarduims/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=clusterrolebindings;rolebindings,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=clusterroles;roles,verbs=bind
+//+kubebuilder:rbac:groups=user.openshift.io,resources=groups,verbs=get;list;update
+//+kubebuilder:rbac:groups=user.openshift.io,resources=users,verbs=get
+//+kubebuilder:rbac:groups=oauth.openshift.io,resources=oauthaccesstokens;oauthauthorizetokens,verbs=list;delete
+//+kubebuilder:rbac:groups="",resources=nodes,verbs=get;list
+//+kubebuilder:rbac:groups="",resources=nodes/proxy,verbs=get
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;create;update
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 func (r *GuarduimReconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
 	log := r.Log.WithValues("guarduim", req.NamespacedName)
@@ -42,133 +84,505 @@ func (r *GuarduimReconciler) Reconcile(ctx context.Context, req reconcile.Reques
 		return reconcile.Result{}, err
 	}
 
-	// Execute command to count authentication failures
-	cmd := exec.Command("sh", "-c",
-		`oc adm node-logs --role=master --path=oauth-server/audit.log | grep 'authentication.openshift.io/decision":"deny' | grep 'authentication.openshift.io/username":"`+guarduim.Spec.Username+`"' | wc -l`)
+	// Restore the user's access before the Guarduim goes away
+	if !guarduim.DeletionTimestamp.IsZero() {
+		if err := r.finalize(ctx, guarduim); err != nil {
+			log.Error(err, "Failed to restore access of deleted Guarduim")
+			return reconcile.Result{}, err
+		}
+		return reconcile.Result{}, nil
+	}
+	if controllerutil.AddFinalizer(guarduim, v1.Finalizer) {
+		if err := r.Client.Update(ctx, guarduim); err != nil {
+			log.Error(err, "Failed to add finalizer")
+			return reconcile.Result{}, err
+		}
+	}
+
+	now := time.Now()
+	guarduim.Status.ObservedGeneration = guarduim.Generation
+	guarduim.Status.LastCheckedTime = &metav1.Time{Time: now}
 
-	output, err := cmd.Output()
+	// Honor a manual unblock before counting
+	if reason, ok := guarduim.Annotations[v1.UnblockAnnotation]; ok {
+		if err := r.manualUnblock(ctx, guarduim, reason); err != nil {
+			log.Error(err, "Failed to unblock user on request")
+			r.recordFailure(ctx, guarduim, v1.ConditionEnforcementApplied, reasonUnblockFailed, err)
+			return reconcile.Result{}, err
+		}
+	}
+
+	// Look up the failures indexed by the shared log scanner
+	sourceName, scanner, err := r.scannerFor(guarduim)
 	if err != nil {
-		log.Error(err, "Failed to execute command")
+		log.Error(err, "Failed to select log source")
+		r.recordFailure(ctx, guarduim, v1.ConditionLogSourceAvailable, reasonUnknownLogSource, err)
 		return reconcile.Result{}, err
 	}
-
-	// Parse the failure count
-	failureCount := strings.TrimSpace(string(output))
-	count := 0
-	if failureCount != "" {
-		count, err = strconv.Atoi(failureCount)
-		if err != nil {
-			log.Error(err, "Failed to convert failure count to int")
+	if !scanner.Scanned() {
+		log.Info("Waiting for the first read of the log source", "logSource", sourceName)
+		message := fmt.Sprintf("Waiting for the first read of log source %q", sourceName)
+		setCondition(guarduim, v1.ConditionLogSourceAvailable, metav1.ConditionUnknown, reasonWaitingForLogSource, message)
+		setCondition(guarduim, v1.ConditionReady, metav1.ConditionUnknown, reasonWaitingForLogSource, message)
+		if err := r.Client.Status().Update(ctx, guarduim); err != nil {
+			log.Error(err, "Failed to update Guarduim status")
 			return reconcile.Result{}, err
 		}
+		return reconcile.Result{RequeueAfter: 5 * time.Second}, nil
+	}
+	if err := scanner.Err(); err != nil {
+		log.Error(err, "Failed to list authentication events")
+		r.recordFailure(ctx, guarduim, v1.ConditionLogSourceAvailable, reasonLogSourceFailed, err)
+		return reconcile.Result{}, err
+	}
+
+	var failures []metav1.MicroTime
+	var lastRecorded time.Time
+	if guarduim.Status.LogSource == sourceName {
+		failures = guarduim.Status.Failures
+		if n := len(failures); n > 0 {
+			lastRecorded = failures[n-1].Time
+		}
+	}
+	events := scanner.Failures(guarduim.Spec.Username, lastRecorded)
+
+	// Lift the block once its duration has passed
+	if until := guarduim.Status.BlockedUntil; guarduim.Status.Blocked && until != nil && !now.Before(until.Time) {
+		log.Info("Block expired", "blockedAt", guarduim.Status.BlockedAt, "blockedUntil", until)
+		guarduim.Status.Blocked = false
+		blockActionsTotal.WithLabelValues(actionUnblock).Inc()
+		r.recordUserEvent(ctx, guarduim, corev1.EventTypeNormal, eventUnblocked, "Block of user %q expired", guarduim.Spec.Username)
+	}
+
+	// Only count failures inside the sliding window
+	var windowStart time.Time
+	if window := guarduim.Spec.Window; window != nil && window.Duration > 0 {
+		windowStart = now.Add(-window.Duration)
+	}
+	// Failures up to the end of the last block must not block the user again
+	if until := guarduim.Status.BlockedUntil; !guarduim.Status.Blocked && until != nil && until.Time.After(windowStart) {
+		windowStart = until.Time
+	}
+	failures = recordFailures(failures, events, guarduim.Spec.Username, windowStart)
+	count := len(failures)
+	recordFailureTimes(&guarduim.Status, events)
+	guarduim.Status.SourceIPs = sourceIPs(scanner.Failures(guarduim.Spec.Username, windowStart))
+
+	// Report a successful login following failed logins, likely with a
+	// guessed or leaked password
+	if threshold := guarduim.Spec.SuccessAfterFailures; threshold > 0 {
+		login := suspiciousLogin(scanner.Failures(guarduim.Spec.Username, windowStart),
+			scanner.Logins(guarduim.Spec.Username, windowStart), threshold)
+		if last := guarduim.Status.SuspiciousLogin; login != nil && (last == nil || login.Time.After(last.Time.Time)) {
+			guarduim.Status.SuspiciousLogin = login
+			suspiciousLoginsTotal.WithLabelValues(usernameLabel(guarduim)).Inc()
+			log.Info("Suspicious login", "failureCount", login.FailureCount, "sourceIP", login.SourceIP)
+			r.recordUserEvent(ctx, guarduim, corev1.EventTypeWarning, eventSuspiciousLogin,
+				"User %q logged in after %d consecutive failed logins", guarduim.Spec.Username, login.FailureCount)
+		}
+	}
+
+	// Report logins from unusual locations
+	anomalous := false
+	if detection := guarduim.Spec.LocationDetection; detection != nil && r.GeoIP != nil {
+		after := windowStart
+		if checked := guarduim.Status.LocationsCheckedUntil; checked != nil && checked.After(after) {
+			after = checked.Time
+		}
+		logins := append(scanner.Failures(guarduim.Spec.Username, after), scanner.Logins(guarduim.Spec.Username, after)...)
+		sort.SliceStable(logins, func(i, j int) bool { return logins[i].Timestamp.Before(logins[j].Timestamp) })
+		anomalies := checkLocations(&guarduim.Status, logins, r.GeoIP, *detection)
+		for _, anomaly := range anomalies {
+			locationAnomaliesTotal.WithLabelValues(string(anomaly.Type)).Inc()
+			log.Info("Login from an unusual location", "type", anomaly.Type, "sourceIP", anomaly.SourceIP)
+			r.recordUserEvent(ctx, guarduim, corev1.EventTypeWarning, string(anomaly.Type),
+				"Login of user %q from %s, %s", guarduim.Spec.Username, anomaly.SourceIP, anomaly.Message)
+		}
+		recordLocationAnomalies(&guarduim.Status, anomalies)
+		anomalous = detection.Block && recentLocationAnomaly(&guarduim.Status, windowStart) != nil
+	}
+
+	// Evaluate the custom detection rules against the login attempts in the
+	// window. Rules that do not compile or evaluate are reported, the others
+	// still apply.
+	var matchedRules []string
+	var rulesErr error
+	if len(guarduim.Spec.Rules) > 0 {
+		// The failures_<duration> variables look past a shorter window, but
+		// not past the end of the last block either
+		recentStart := now.Add(-rules.RecentWindow)
+		if until := guarduim.Status.BlockedUntil; !guarduim.Status.Blocked && until != nil && until.Time.After(recentStart) {
+			recentStart = until.Time
+		}
+		matchedRules, rulesErr = matchRules(guarduim.Spec.Rules, rules.Input{
+			Username:       guarduim.Spec.Username,
+			Failures:       scanner.Failures(guarduim.Spec.Username, windowStart),
+			Logins:         scanner.Logins(guarduim.Spec.Username, windowStart),
+			RecentFailures: scanner.Failures(guarduim.Spec.Username, recentStart),
+			Now:            now,
+		})
+		if rulesErr != nil {
+			log.Error(rulesErr, "Failed to evaluate detection rules")
+		}
+		for _, name := range matchedRules {
+			if slices.Contains(guarduim.Status.MatchedRules, name) {
+				continue
+			}
+			countRuleMatch(&guarduim.Status, name, now)
+			ruleMatchesTotal.WithLabelValues(name).Inc()
+			log.Info("Detection rule matched", "rule", name)
+			r.recordUserEvent(ctx, guarduim, corev1.EventTypeWarning, eventRuleMatched,
+				"Login attempts of user %q match rule %q", guarduim.Spec.Username, name)
+		}
 	}
+	guarduim.Status.MatchedRules = matchedRules
+	guarduim.Status.RuleMatches = slices.DeleteFunc(guarduim.Status.RuleMatches, func(matches v1.RuleMatches) bool {
+		return !slices.ContainsFunc(guarduim.Spec.Rules, func(rule v1.DetectionRule) bool { return rule.Name == matches.Name })
+	})
 
 	// Update the status
 	guarduim.Status.FailureCount = count
-	guarduim.Status.Blocked = count > guarduim.Spec.Threshold
+	guarduim.Status.Failures = failures
+	guarduim.Status.LogSource = sourceName
+	guarduim.Status.WindowEnd = &metav1.Time{Time: now}
+	guarduim.Status.WindowStart = nil
+	if !windowStart.IsZero() {
+		guarduim.Status.WindowStart = &metav1.Time{Time: windowStart}
+	}
 
-	err = r.Client.Status().Update(ctx, guarduim)
+	// Exempted users are counted but never blocked
+	exemption, err := r.exemption(ctx, guarduim)
 	if err != nil {
-		log.Error(err, "Failed to update Guarduim status")
+		log.Error(err, "Failed to look up exemptions")
+		r.recordFailure(ctx, guarduim, v1.ConditionEnforcementApplied, reasonExemptionCheckFailed, err)
 		return reconcile.Result{}, err
 	}
+	if exemption != "" && guarduim.Status.Blocked {
+		guarduim.Status.Blocked = false
+		blockActionsTotal.WithLabelValues(actionUnblock).Inc()
+		r.recordUserEvent(ctx, guarduim, corev1.EventTypeNormal, eventUnblocked,
+			"Unblocked user %q, exempted by %s", guarduim.Spec.Username, exemption)
+	}
+
+	// Only the Enforce mode keeps a user blocked, the others restore its access
+	mode := r.enforcementMode(guarduim)
+	if mode != v1.EnforcementModeEnforce && guarduim.Status.Blocked {
+		guarduim.Status.Blocked = false
+		blockActionsTotal.WithLabelValues(actionUnblock).Inc()
+		r.recordUserEvent(ctx, guarduim, corev1.EventTypeNormal, eventUnblocked,
+			"Unblocked user %q, the enforcement mode is %s", guarduim.Spec.Username, mode)
+	}
+
+	// The user exceeds the threshold, matches a detection rule, or logged in
+	// from an unusual location with blocking on anomalies, for cause. A rule
+	// only blocks a user who failed to log in, so that a rule matching anyone,
+	// such as "true", can not lock out a user like a threshold of 0 could not
+	exceeded := count > guarduim.Spec.Threshold
+	cause := fmt.Sprintf("%d failed logins, threshold %d", count, guarduim.Spec.Threshold)
+	switch {
+	case exceeded:
+	case len(matchedRules) > 0 && count > 0:
+		exceeded, cause = true, fmt.Sprintf("matching rule %q", matchedRules[0])
+	case anomalous:
+		exceeded, cause = true, "a login from an unusual location, "+guarduim.Status.LocationAnomalies[0].Message
+	}
+
+	// unenforced is the reason a threshold exceeded does not block the user
+	var unenforced, unenforcedBy string
+	switch {
+	case !guarduim.Status.Blocked && exceeded && exemption != "":
+		unenforced, unenforcedBy = reasonExempt, "exempted by "+exemption
+	case !guarduim.Status.Blocked && exceeded && mode == v1.EnforcementModeDryRun:
+		unenforced, unenforcedBy = reasonDryRun, "the enforcement mode is DryRun"
+	case !guarduim.Status.Blocked && exceeded && mode == v1.EnforcementModeDisabled:
+		unenforced, unenforcedBy = reasonEnforcementDisabled, "the enforcement mode is Disabled"
+	case !guarduim.Status.Blocked && exceeded:
+		startBlock(guarduim, now)
+		blockActionsTotal.WithLabelValues(actionBlock).Inc()
+		log.Info("Blocking user", "failureCount", count, "blockCount", guarduim.Status.BlockCount,
+			"blockedUntil", guarduim.Status.BlockedUntil)
+		r.recordUserEvent(ctx, guarduim, corev1.EventTypeWarning, eventBlocked,
+			"Blocked user %q after %s", guarduim.Spec.Username, cause)
+	case guarduim.Status.Blocked && guarduim.Status.BlockedUntil == nil && !exceeded:
+		// Without a duration the block lasts while the threshold is exceeded
+		guarduim.Status.Blocked = false
+		blockActionsTotal.WithLabelValues(actionUnblock).Inc()
+		r.recordUserEvent(ctx, guarduim, corev1.EventTypeNormal, eventUnblocked,
+			"Unblocked user %q, %d failed logins are within the threshold of %d", guarduim.Spec.Username, count, guarduim.Spec.Threshold)
+	}
+
+	if blocked := meta.FindStatusCondition(guarduim.Status.Conditions, v1.ConditionBlocked); unenforced != "" &&
+		(blocked == nil || blocked.Reason != unenforced) {
+		log.Info("Threshold exceeded, not blocking", "failureCount", count, "reason", unenforcedBy)
+		switch unenforced {
+		case reasonDryRun:
+			r.recordUserEvent(ctx, guarduim, corev1.EventTypeWarning, eventWouldBlock,
+				"Would block user %q after %s", guarduim.Spec.Username, cause)
+		case reasonExempt:
+			r.recordUserEvent(ctx, guarduim, corev1.EventTypeWarning, eventThresholdExceeded,
+				"User %q not blocked after %s: %s", guarduim.Spec.Username, cause, unenforcedBy)
+		}
+	}
 
-	// Block user if threshold exceeded, otherwise unblock
+	// Revoke the user's access if threshold exceeded, otherwise restore it
 	if guarduim.Status.Blocked {
-		err := r.blockUser(ctx, guarduim.Spec.Username)
+		err := r.revokeAccess(ctx, guarduim)
 		if err != nil {
 			log.Error(err, "Failed to block user")
+			r.recordFailure(ctx, guarduim, v1.ConditionEnforcementApplied, reasonRevokeFailed, err)
+			return reconcile.Result{}, err
+		}
+
+		// Cut off the sessions the user already holds
+		revoked, err := r.revokeTokens(ctx, guarduim.Spec.Username, guarduim.Spec.RevokeAuthorizeTokens)
+		guarduim.Status.RevokedTokens += revoked
+		blockActionsTotal.WithLabelValues(actionRevokeTokens).Add(float64(revoked))
+		if revoked > 0 {
+			r.recordUserEvent(ctx, guarduim, corev1.EventTypeNormal, eventTokensRevoked,
+				"Revoked %d OAuth tokens of user %q", revoked, guarduim.Spec.Username)
+		}
+		if err != nil {
+			log.Error(err, "Failed to revoke OAuth tokens")
+			r.recordFailure(ctx, guarduim, v1.ConditionEnforcementApplied, reasonTokenRevocationFailed, err)
 			return reconcile.Result{}, err
 		}
 	} else {
-		err := r.unblockUser(ctx, guarduim.Spec.Username)
+		err := r.restoreAccess(ctx, guarduim)
 		if err != nil {
 			log.Error(err, "Failed to unblock user")
+			r.recordFailure(ctx, guarduim, v1.ConditionEnforcementApplied, reasonRestoreFailed, err)
 			return reconcile.Result{}, err
 		}
+		guarduim.Status.RevokedTokens = 0
+	}
+	setCheckedConditions(guarduim, sourceName)
+	if unenforced != "" {
+		setCondition(guarduim, v1.ConditionBlocked, metav1.ConditionFalse, unenforced,
+			fmt.Sprintf("Not blocked after %s: %s", cause, unenforcedBy))
+	}
+	setWouldBlockCondition(guarduim, mode, unenforced, cause)
+	setSuspiciousLoginCondition(guarduim, windowStart)
+	setLocationAnomalyCondition(guarduim, windowStart, r.GeoIP != nil)
+	setRuleMatchedCondition(guarduim, rulesErr)
+
+	err = r.Client.Status().Update(ctx, guarduim)
+	if err != nil {
+		log.Error(err, "Failed to update Guarduim status")
+		return reconcile.Result{}, err
 	}
 
-	// Requeue after 30 seconds
+	// Requeue after 30 seconds, or when the block expires if sooner
+	requeueAfter := 30 * time.Second
+	if until := guarduim.Status.BlockedUntil; guarduim.Status.Blocked && until != nil && until.Sub(now) < requeueAfter {
+		requeueAfter = until.Sub(now)
+	}
 	return reconcile.Result{
-		RequeueAfter: 30 * time.Second,
+		RequeueAfter: requeueAfter,
 	}, nil
 }
 
-// createBlockedUserClusterRole ensures the ClusterRole exists
-func (r *GuarduimReconciler) createBlockedUserClusterRole(ctx context.Context) error {
-	clusterRole := &rbacv1.ClusterRole{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: "blocked-user",
-		},
-		Rules: []rbacv1.PolicyRule{}, // Empty rules mean no permissions
-	}
+// manualUnblock unblocks the user as requested by UnblockAnnotation, forgets
+// the failures counted so far and removes the annotations once recorded
+func (r *GuarduimReconciler) manualUnblock(ctx context.Context, guarduim *v1.Guarduim, reason string) error {
+	by := guarduim.Annotations[v1.UnblockedByAnnotation]
+	wasBlocked := guarduim.Status.Blocked
+	now := metav1.Now()
 
-	// Check if ClusterRole already exists
-	err := r.Client.Get(ctx, client.ObjectKey{Name: "blocked-user"}, clusterRole)
-	if err != nil {
-		if errors.IsNotFound(err) {
-			return r.Client.Create(ctx, clusterRole)
-		}
+	guarduim.Status.Blocked = false
+	guarduim.Status.BlockedUntil = &now
+	guarduim.Status.FailureCount = 0
+	guarduim.Status.Failures = nil
+	guarduim.Status.LastUnblock = &v1.Unblock{Time: now, By: by, Reason: reason}
+	if err := r.Client.Status().Update(ctx, guarduim); err != nil {
 		return err
 	}
+	if wasBlocked {
+		blockActionsTotal.WithLabelValues(actionUnblock).Inc()
+	}
+	r.Log.Info("Unblocked user on request", "guarduim", client.ObjectKeyFromObject(guarduim), "by", by, "reason", reason)
+	r.recordUserEvent(ctx, guarduim, corev1.EventTypeNormal, eventUnblocked, "Unblocked by %q: %s", by, reason)
 
-	return nil
+	delete(guarduim.Annotations, v1.UnblockAnnotation)
+	delete(guarduim.Annotations, v1.UnblockedByAnnotation)
+	return r.Client.Update(ctx, guarduim)
 }
 
-// blockUser creates a ClusterRoleBinding for the blocked user
-func (r *GuarduimReconciler) blockUser(ctx context.Context, username string) error {
-	// Ensure the ClusterRole exists
-	if err := r.createBlockedUserClusterRole(ctx); err != nil {
+// enforcementMode returns the enforcement mode of the Guarduim, defaulting
+// to that of the manager
+func (r *GuarduimReconciler) enforcementMode(guarduim *v1.Guarduim) v1.EnforcementMode {
+	if mode := guarduim.Spec.EnforcementMode; mode != "" {
+		return mode
+	}
+	if r.DefaultEnforcementMode != "" {
+		return r.DefaultEnforcementMode
+	}
+	return v1.EnforcementModeEnforce
+}
+
+// finalize restores the access of the user of a deleted Guarduim, deletes the
+// binding older versions blocked it with and removes the finalizer
+func (r *GuarduimReconciler) finalize(ctx context.Context, guarduim *v1.Guarduim) error {
+	if !controllerutil.ContainsFinalizer(guarduim, v1.Finalizer) {
+		return nil
+	}
+
+	if err := r.restoreAccess(ctx, guarduim); err != nil {
+		r.recordFailure(ctx, guarduim, v1.ConditionEnforcementApplied, reasonRestoreFailed, err)
 		return err
 	}
+	if err := deleteLegacyBinding(ctx, r.Client, guarduim.Spec.Username); err != nil {
+		return err
+	}
+	if guarduim.Status.Blocked {
+		blockActionsTotal.WithLabelValues(actionUnblock).Inc()
+		r.recordUserEvent(ctx, guarduim, corev1.EventTypeNormal, eventUnblocked,
+			"Unblocked user %q, its Guarduim was deleted", guarduim.Spec.Username)
+	}
 
-	clusterRoleBinding := &rbacv1.ClusterRoleBinding{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: "block-user-" + username,
-		},
-		Subjects: []rbacv1.Subject{
-			{
-				Kind:     "User",
-				Name:     username,
-				APIGroup: "rbac.authorization.k8s.io",
-			},
-		},
-		RoleRef: rbacv1.RoleRef{
-			Kind:     "ClusterRole",
-			Name:     "blocked-user",
-			APIGroup: "rbac.authorization.k8s.io",
-		},
-	}
-
-	// Check if ClusterRoleBinding exists
-	err := r.Client.Get(ctx, client.ObjectKey{Name: "block-user-" + username}, clusterRoleBinding)
-	if err != nil {
-		if errors.IsNotFound(err) {
-			return r.Client.Create(ctx, clusterRoleBinding)
+	controllerutil.RemoveFinalizer(guarduim, v1.Finalizer)
+	return r.Client.Update(ctx, guarduim)
+}
+
+// startBlock blocks the user from now on for the duration of its next block
+func startBlock(guarduim *v1.Guarduim, now time.Time) {
+	guarduim.Status.Blocked = true
+	guarduim.Status.BlockCount++
+	guarduim.Status.BlockedAt = &metav1.Time{Time: now}
+	guarduim.Status.BlockedUntil = nil
+	if duration := blockDuration(guarduim.Spec, guarduim.Status.BlockCount); duration > 0 {
+		guarduim.Status.BlockedUntil = &metav1.Time{Time: now.Add(duration)}
+	}
+}
+
+// blockDuration returns how long the blockCount-th block of a user lasts:
+// spec.blockDuration doubled for every earlier block, up to spec.maxBlockDuration
+func blockDuration(spec v1.GuarduimSpec, blockCount int) time.Duration {
+	if spec.BlockDuration == nil || spec.BlockDuration.Duration <= 0 {
+		return 0
+	}
+	var limit time.Duration = math.MaxInt64
+	if spec.MaxBlockDuration != nil && spec.MaxBlockDuration.Duration > 0 {
+		limit = spec.MaxBlockDuration.Duration
+	}
+
+	duration := spec.BlockDuration.Duration
+	for i := 1; i < blockCount && duration < limit; i++ {
+		if duration > limit/2 {
+			return limit
 		}
-		return err
+		duration *= 2
 	}
+	return min(duration, limit)
+}
 
-	return nil
+// scannerFor returns the name and scanner of the log source selected by the Guarduim spec
+func (r *GuarduimReconciler) scannerFor(guarduim *v1.Guarduim) (string, *Scanner, error) {
+	return lookupScanner(r.Scanners, r.DefaultLogSource, guarduim.Spec.LogSource)
 }
 
-// unblockUser removes the ClusterRoleBinding
-func (r *GuarduimReconciler) unblockUser(ctx context.Context, username string) error {
-	clusterRoleBinding := &rbacv1.ClusterRoleBinding{}
-	err := r.Client.Get(ctx, client.ObjectKey{Name: "block-user-" + username}, clusterRoleBinding)
-	if err != nil {
-		if errors.IsNotFound(err) {
-			return nil // Already unblocked
+// lookupScanner returns the name and scanner of the named log source, or of
+// defaultName if name is empty
+func lookupScanner(scanners map[string]*Scanner, defaultName, name string) (string, *Scanner, error) {
+	if name == "" {
+		name = defaultName
+	}
+
+	scanner, ok := scanners[name]
+	if !ok {
+		return name, nil, fmt.Errorf("unknown log source %q", name)
+	}
+	return name, scanner, nil
+}
+
+// recordFailures adds the denied logins for username among events to
+// failures, drops those no later than windowStart and returns the most recent
+// maxTrackedFailures, oldest first. A zero windowStart keeps every failure.
+func recordFailures(failures []metav1.MicroTime, events []auditlog.Event, username string, windowStart time.Time) []metav1.MicroTime {
+	for _, event := range events {
+		if event.Denied() && event.Username == username {
+			failures = append(failures, metav1.MicroTime{Time: event.Timestamp})
+		}
+	}
+	sort.SliceStable(failures, func(i, j int) bool { return failures[i].Before(&failures[j]) })
+
+	start := 0
+	for start < len(failures) && !windowStart.IsZero() && !failures[start].Time.After(windowStart) {
+		start++
+	}
+	if len(failures)-start > maxTrackedFailures {
+		start = len(failures) - maxTrackedFailures
+	}
+	return failures[start:]
+}
+
+// recordFailureTimes moves FirstFailure and LastFailure of status to include
+// the denied logins among events
+func recordFailureTimes(status *v1.GuarduimStatus, events []auditlog.Event) {
+	for _, event := range events {
+		if !event.Denied() {
+			continue
+		}
+		if status.FirstFailure == nil || event.Timestamp.Before(status.FirstFailure.Time) {
+			status.FirstFailure = &metav1.Time{Time: event.Timestamp}
+		}
+		if status.LastFailure == nil || event.Timestamp.After(status.LastFailure.Time) {
+			status.LastFailure = &metav1.Time{Time: event.Timestamp}
 		}
-		return err
 	}
+}
 
-	return r.Client.Delete(ctx, clusterRoleBinding)
+// sourceIPs counts the failures, which are oldest first, by client address.
+// The addresses are returned most recent first, at most maxSourceIPs of them.
+func sourceIPs(failures []auditlog.Event) []v1.SourceIP {
+	var ips []v1.SourceIP
+	seen := map[string]int{}
+	for i := len(failures) - 1; i >= 0; i-- {
+		address := failures[i].ClientIP()
+		if address == "" {
+			continue
+		}
+		if j, ok := seen[address]; ok {
+			ips[j].FailureCount++
+		} else if len(ips) < maxSourceIPs {
+			seen[address] = len(ips)
+			ips = append(ips, v1.SourceIP{Address: address, FailureCount: 1})
+		}
+	}
+	return ips
+}
+
+// suspiciousLogin returns the last of the logins that followed at least
+// threshold consecutive failures, or nil. Both lists are oldest first.
+func suspiciousLogin(failures, logins []auditlog.Event, threshold int) *v1.SuspiciousLogin {
+	var login *v1.SuspiciousLogin
+	streak, next := 0, 0
+	for _, event := range logins {
+		for ; next < len(failures) && failures[next].Timestamp.Before(event.Timestamp); next++ {
+			streak++
+		}
+		if streak >= threshold {
+			login = &v1.SuspiciousLogin{
+				Time:         metav1.MicroTime{Time: event.Timestamp},
+				FailureCount: streak,
+				SourceIP:     event.ClientIP(),
+			}
+		}
+		streak = 0
+	}
+	return login
 }
 
 // SetupWithManager sets up the controller with the Manager.
+// Status updates do not trigger a reconcile, the periodic requeue covers them.
 func (r *GuarduimReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := metrics.Registry.Register(newBlockedUsersGauge(mgr.GetClient())); err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&v1.Guarduim{}).
+		For(&v1.Guarduim{}, builder.WithPredicates(predicate.Or(
+			predicate.GenerationChangedPredicate{},
+			predicate.AnnotationChangedPredicate{},
+		))).
 		Complete(r)
 }
@@ -18,18 +18,72 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	guardv1 "github.com/SaifRehman/guarduim/api/v1"
+	"github.com/SaifRehman/guarduim/internal/auditlog"
 )
 
+// staticLogSource returns the same events on every List.
+type staticLogSource []auditlog.Event
+
+func (s staticLogSource) List(_ context.Context, since Cursor) ([]auditlog.Event, Cursor, error) {
+	return s, since, nil
+}
+
+// deleteGuarduim deletes a Guarduim and lets the controller finalize it.
+func deleteGuarduim(ctx context.Context, guarduim *guardv1.Guarduim) error {
+	if err := k8sClient.Delete(ctx, guarduim); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	reconciler, _ := newTestReconciler(nil)
+	_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(guarduim)})
+	return err
+}
+
+// newTestScanner returns a scanner that has read source once.
+func newTestScanner(ctx context.Context, source LogSource) *Scanner {
+	scanner := &Scanner{Source: source, Retention: time.Hour}
+	scanner.scan(ctx)
+	return scanner
+}
+
+// newTestReconciler returns a GuarduimReconciler reading the events of scanner,
+// revoking tokens through a fake dynamic client and recording Events in the
+// returned fake recorder.
+func newTestReconciler(scanner *Scanner) (*GuarduimReconciler, *record.FakeRecorder) {
+	recorder := record.NewFakeRecorder(10)
+	return &GuarduimReconciler{
+		Client:           k8sClient,
+		Scheme:           k8sClient.Scheme(),
+		Scanners:         map[string]*Scanner{OAuthServerLogSource: scanner},
+		DefaultLogSource: OAuthServerLogSource,
+		Dynamic:          dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), tokenListKinds),
+		Recorder:         recorder,
+	}, recorder
+}
+
+// failingLogSource fails every List.
+type failingLogSource struct{}
+
+func (failingLogSource) List(_ context.Context, since Cursor) ([]auditlog.Event, Cursor, error) {
+	return nil, since, fmt.Errorf("node proxy unavailable")
+}
+
 var _ = Describe("Guarduim Controller", func() {
 	Context("When reconciling a resource", func() {
 		const resourceName = "test-resource"
@@ -51,7 +105,10 @@ var _ = Describe("Guarduim Controller", func() {
 						Name:      resourceName,
 						Namespace: "default",
 					},
-					// TODO(user): Specify other spec details if needed.
+					Spec: guardv1.GuarduimSpec{
+						Username:  "alice",
+						Threshold: 5,
+					},
 				}
 				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
 			}
@@ -64,21 +121,371 @@ var _ = Describe("Guarduim Controller", func() {
 			Expect(err).NotTo(HaveOccurred())
 
 			By("Cleanup the specific resource instance Guarduim")
-			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			Expect(deleteGuarduim(ctx, resource)).To(Succeed())
 		})
 		It("should successfully reconcile the resource", func() {
 			By("Reconciling the created resource")
-			controllerReconciler := &GuarduimReconciler{
-				Client: k8sClient,
-				Scheme: k8sClient.Scheme(),
-			}
+			controllerReconciler, _ := newTestReconciler(newTestScanner(ctx, staticLogSource{
+				{Username: "alice", Decision: auditlog.DecisionDeny, Timestamp: time.Now().Add(-time.Minute)},
+			}))
 
 			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
 				NamespacedName: typeNamespacedName,
 			})
 			Expect(err).NotTo(HaveOccurred())
-			// TODO(user): Add more specific assertions depending on your controller's reconciliation logic.
-			// Example: If you expect a certain status condition after reconciliation, verify it here.
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, guarduim)).To(Succeed())
+			Expect(guarduim.Status.FailureCount).To(Equal(1))
+			Expect(guarduim.Status.Blocked).To(BeFalse())
+			Expect(guarduim.Status.ObservedGeneration).To(Equal(guarduim.Generation))
+			Expect(guarduim.Status.LastCheckedTime).NotTo(BeNil())
+			Expect(meta.IsStatusConditionTrue(guarduim.Status.Conditions, guardv1.ConditionReady)).To(BeTrue())
+			Expect(meta.IsStatusConditionFalse(guarduim.Status.Conditions, guardv1.ConditionBlocked)).To(BeTrue())
+		})
+
+		It("should report a log source that can not be read", func() {
+			controllerReconciler, recorder := newTestReconciler(newTestScanner(ctx, failingLogSource{}))
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).To(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, guarduim)).To(Succeed())
+			condition := meta.FindStatusCondition(guarduim.Status.Conditions, guardv1.ConditionLogSourceAvailable)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Message).To(ContainSubstring("node proxy unavailable"))
+			Expect(recorder.Events).To(Receive(HavePrefix("Warning LogSourceFailed")))
+			Expect(meta.IsStatusConditionFalse(guarduim.Status.Conditions, guardv1.ConditionReady)).To(BeTrue())
 		})
 	})
 })
+
+var _ = Describe("Block expiry", func() {
+	ctx := context.Background()
+	key := types.NamespacedName{Name: "carol", Namespace: "default"}
+
+	var (
+		blockedUntil time.Time
+		recorder     *record.FakeRecorder
+	)
+
+	BeforeEach(func() {
+		resource := &guardv1.Guarduim{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+			Spec: guardv1.GuarduimSpec{
+				Username:         "carol",
+				Threshold:        1,
+				Window:           &metav1.Duration{Duration: time.Hour},
+				BlockDuration:    &metav1.Duration{Duration: 30 * time.Minute},
+				MaxBlockDuration: &metav1.Duration{Duration: 24 * time.Hour},
+			},
+		}
+		Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+		blockedUntil = time.Now().Add(-time.Minute)
+		resource.Status.Blocked = true
+		resource.Status.BlockCount = 1
+		resource.Status.BlockedAt = &metav1.Time{Time: blockedUntil.Add(-30 * time.Minute)}
+		resource.Status.BlockedUntil = &metav1.Time{Time: blockedUntil}
+		Expect(k8sClient.Status().Update(ctx, resource)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(deleteGuarduim(ctx, &guardv1.Guarduim{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+		})).To(Succeed())
+	})
+
+	reconcileWith := func(events ...auditlog.Event) *guardv1.Guarduim {
+		var reconciler *GuarduimReconciler
+		reconciler, recorder = newTestReconciler(newTestScanner(ctx, staticLogSource(events)))
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		guarduim := &guardv1.Guarduim{}
+		Expect(k8sClient.Get(ctx, key, guarduim)).To(Succeed())
+		return guarduim
+	}
+
+	It("unblocks the user once the block expired, ignoring the failures that caused it", func() {
+		guarduim := reconcileWith(
+			auditlog.Event{Username: "carol", Decision: auditlog.DecisionDeny, Timestamp: blockedUntil.Add(-40 * time.Minute)},
+			auditlog.Event{Username: "carol", Decision: auditlog.DecisionDeny, Timestamp: blockedUntil.Add(-35 * time.Minute)},
+		)
+		Expect(guarduim.Status.Blocked).To(BeFalse())
+		Expect(guarduim.Status.FailureCount).To(BeZero())
+		Expect(guarduim.Status.BlockCount).To(Equal(1))
+		Expect(recorder.Events).To(Receive(HavePrefix("Normal Unblocked")))
+	})
+
+	It("blocks a repeat offender for twice as long", func() {
+		guarduim := reconcileWith(
+			auditlog.Event{Username: "carol", Decision: auditlog.DecisionDeny, Timestamp: blockedUntil.Add(10 * time.Second)},
+			auditlog.Event{Username: "carol", Decision: auditlog.DecisionDeny, Timestamp: blockedUntil.Add(20 * time.Second)},
+		)
+		Expect(guarduim.Status.Blocked).To(BeTrue())
+		Expect(guarduim.Status.BlockCount).To(Equal(2))
+		Expect(guarduim.Status.BlockedUntil.Sub(guarduim.Status.BlockedAt.Time)).To(Equal(time.Hour))
+		Expect(recorder.Events).To(Receive(HavePrefix("Normal Unblocked")))
+		Expect(recorder.Events).To(Receive(HavePrefix("Warning Blocked")))
+	})
+})
+
+var _ = Describe("Manual unblock", func() {
+	ctx := context.Background()
+	key := types.NamespacedName{Name: "dave", Namespace: "default"}
+
+	It("unblocks the user, records who asked and why, and forgets earlier failures", func() {
+		failedAt := time.Now().Add(-time.Minute)
+		resource := &guardv1.Guarduim{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace, Annotations: map[string]string{
+				guardv1.UnblockAnnotation:     "identity verified by phone",
+				guardv1.UnblockedByAnnotation: "helpdesk",
+			}},
+			Spec: guardv1.GuarduimSpec{Username: "dave", Threshold: 1},
+		}
+		Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		DeferCleanup(deleteGuarduim, ctx, resource)
+
+		resource.Status.Blocked = true
+		resource.Status.Failures = []metav1.MicroTime{{Time: failedAt}, {Time: failedAt}}
+		resource.Status.FailureCount = 2
+		Expect(k8sClient.Status().Update(ctx, resource)).To(Succeed())
+
+		reconciler, recorder := newTestReconciler(newTestScanner(ctx, staticLogSource{
+			{Username: "dave", Decision: auditlog.DecisionDeny, Timestamp: failedAt},
+			{Username: "dave", Decision: auditlog.DecisionDeny, Timestamp: failedAt},
+		}))
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		guarduim := &guardv1.Guarduim{}
+		Expect(k8sClient.Get(ctx, key, guarduim)).To(Succeed())
+		Expect(guarduim.Status.Blocked).To(BeFalse())
+		Expect(guarduim.Status.FailureCount).To(BeZero())
+		Expect(guarduim.Status.LastUnblock).NotTo(BeNil())
+		Expect(guarduim.Status.LastUnblock.By).To(Equal("helpdesk"))
+		Expect(guarduim.Status.LastUnblock.Reason).To(Equal("identity verified by phone"))
+		Expect(guarduim.Annotations).NotTo(HaveKey(guardv1.UnblockAnnotation))
+		Expect(guarduim.Annotations).NotTo(HaveKey(guardv1.UnblockedByAnnotation))
+		Expect(recorder.Events).To(Receive(ContainSubstring("identity verified by phone")))
+	})
+})
+
+var _ = Describe("Enforcement modes", func() {
+	ctx := context.Background()
+	key := types.NamespacedName{Name: "heidi", Namespace: "default"}
+
+	reconcileIn := func(mode, defaultMode guardv1.EnforcementMode) (*guardv1.Guarduim, *record.FakeRecorder) {
+		resource := &guardv1.Guarduim{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+			Spec:       guardv1.GuarduimSpec{Username: "heidi", Threshold: 1, EnforcementMode: mode},
+		}
+		Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		DeferCleanup(deleteGuarduim, ctx, resource)
+
+		failedAt := time.Now().Add(-time.Minute)
+		reconciler, recorder := newTestReconciler(newTestScanner(ctx, staticLogSource{
+			{Username: "heidi", Decision: auditlog.DecisionDeny, Timestamp: failedAt},
+			{Username: "heidi", Decision: auditlog.DecisionDeny, Timestamp: failedAt},
+		}))
+		reconciler.DefaultEnforcementMode = defaultMode
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		guarduim := &guardv1.Guarduim{}
+		Expect(k8sClient.Get(ctx, key, guarduim)).To(Succeed())
+		return guarduim, recorder
+	}
+
+	It("reports who would be blocked in DryRun mode", func() {
+		guarduim, recorder := reconcileIn(guardv1.EnforcementModeDryRun, "")
+		Expect(guarduim.Status.FailureCount).To(Equal(2))
+		Expect(guarduim.Status.Blocked).To(BeFalse())
+		Expect(guarduim.Status.BlockCount).To(BeZero())
+		Expect(meta.IsStatusConditionTrue(guarduim.Status.Conditions, guardv1.ConditionWouldBlock)).To(BeTrue())
+		Expect(meta.FindStatusCondition(guarduim.Status.Conditions, guardv1.ConditionBlocked).Reason).To(Equal(reasonDryRun))
+		Expect(recorder.Events).To(Receive(HavePrefix("Warning WouldBlock")))
+	})
+
+	It("uses the enforcement mode of the manager by default", func() {
+		guarduim, _ := reconcileIn("", guardv1.EnforcementModeDryRun)
+		Expect(guarduim.Status.Blocked).To(BeFalse())
+		Expect(meta.IsStatusConditionTrue(guarduim.Status.Conditions, guardv1.ConditionWouldBlock)).To(BeTrue())
+	})
+
+	It("unblocks a user blocked before switching to DryRun mode", func() {
+		resource := &guardv1.Guarduim{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+			Spec:       guardv1.GuarduimSpec{Username: "heidi", Threshold: 5, EnforcementMode: guardv1.EnforcementModeDryRun},
+		}
+		Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		DeferCleanup(deleteGuarduim, ctx, resource)
+		resource.Status.Blocked = true
+		resource.Status.BlockCount = 1
+		Expect(k8sClient.Status().Update(ctx, resource)).To(Succeed())
+
+		reconciler, recorder := newTestReconciler(newTestScanner(ctx, staticLogSource{}))
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		guarduim := &guardv1.Guarduim{}
+		Expect(k8sClient.Get(ctx, key, guarduim)).To(Succeed())
+		Expect(guarduim.Status.Blocked).To(BeFalse())
+		Expect(guarduim.Status.BlockCount).To(Equal(1))
+		Expect(recorder.Events).To(Receive(Equal(`Normal Unblocked Unblocked user "heidi", the enforcement mode is DryRun`)))
+	})
+
+	It("only counts failures in Disabled mode", func() {
+		guarduim, recorder := reconcileIn(guardv1.EnforcementModeDisabled, guardv1.EnforcementModeDryRun)
+		Expect(guarduim.Status.FailureCount).To(Equal(2))
+		Expect(guarduim.Status.Blocked).To(BeFalse())
+		Expect(meta.FindStatusCondition(guarduim.Status.Conditions, guardv1.ConditionWouldBlock)).To(BeNil())
+		Expect(meta.FindStatusCondition(guarduim.Status.Conditions, guardv1.ConditionBlocked).Reason).To(Equal(reasonEnforcementDisabled))
+		Expect(recorder.Events).NotTo(Receive())
+	})
+})
+
+var _ = Describe("blockDuration", func() {
+	spec := guardv1.GuarduimSpec{
+		BlockDuration:    &metav1.Duration{Duration: 30 * time.Minute},
+		MaxBlockDuration: &metav1.Duration{Duration: 3 * time.Hour},
+	}
+
+	It("doubles the duration for every earlier block up to the maximum", func() {
+		Expect(blockDuration(spec, 1)).To(Equal(30 * time.Minute))
+		Expect(blockDuration(spec, 2)).To(Equal(time.Hour))
+		Expect(blockDuration(spec, 3)).To(Equal(2 * time.Hour))
+		Expect(blockDuration(spec, 4)).To(Equal(3 * time.Hour))
+		Expect(blockDuration(spec, 1000)).To(Equal(3 * time.Hour))
+	})
+
+	It("does not overflow without a maximum", func() {
+		spec.MaxBlockDuration = &metav1.Duration{}
+		Expect(blockDuration(spec, 1000)).To(BeNumerically(">", 0))
+	})
+
+	It("returns no duration when blocks do not expire", func() {
+		Expect(blockDuration(guardv1.GuarduimSpec{}, 3)).To(BeZero())
+	})
+})
+
+var _ = Describe("recordFailures", func() {
+	now := time.Date(2025, 1, 20, 12, 0, 0, 0, time.UTC)
+	events := []auditlog.Event{
+		{Username: "alice", Decision: auditlog.DecisionDeny, Timestamp: now.Add(-time.Hour)},
+		{Username: "alice", Decision: auditlog.DecisionDeny, Timestamp: now.Add(-10 * time.Minute)},
+		{Username: "alice", Decision: auditlog.DecisionAllow, Timestamp: now.Add(-5 * time.Minute)},
+		{Username: "bob", Decision: auditlog.DecisionDeny, Timestamp: now.Add(-5 * time.Minute)},
+		{Username: "alice", Decision: auditlog.DecisionDeny, Timestamp: now.Add(-time.Minute)},
+	}
+
+	It("records every denied login for the user without a window", func() {
+		Expect(recordFailures(nil, events, "alice", time.Time{})).To(HaveLen(3))
+	})
+
+	It("keeps only denied logins inside the window", func() {
+		Expect(recordFailures(nil, events, "alice", now.Add(-15*time.Minute))).To(Equal([]metav1.MicroTime{
+			{Time: now.Add(-10 * time.Minute)},
+			{Time: now.Add(-time.Minute)},
+		}))
+	})
+
+	It("adds new failures to the ones already recorded", func() {
+		previous := []metav1.MicroTime{{Time: now.Add(-12 * time.Minute)}, {Time: now.Add(-20 * time.Minute)}}
+		Expect(recordFailures(previous, events, "alice", now.Add(-15*time.Minute))).To(HaveLen(3))
+	})
+})
+
+var _ = Describe("sourceIPs", func() {
+	It("counts the failures by client address, most recent first", func() {
+		failures := []auditlog.Event{
+			{SourceIPs: []string{"10.0.0.1"}},
+			{SourceIPs: []string{"10.0.0.2", "10.128.0.5"}, TrustedProxies: 1},
+			{SourceIPs: []string{"10.0.0.1"}},
+			{SourceIPs: []string{"10.0.0.3"}},
+			{},
+		}
+		Expect(sourceIPs(failures)).To(Equal([]guardv1.SourceIP{
+			{Address: "10.0.0.3", FailureCount: 1},
+			{Address: "10.0.0.1", FailureCount: 2},
+			{Address: "10.0.0.2", FailureCount: 1},
+		}))
+	})
+
+	It("keeps at most maxSourceIPs addresses", func() {
+		var failures []auditlog.Event
+		for i := range 2 * maxSourceIPs {
+			failures = append(failures, auditlog.Event{SourceIPs: []string{fmt.Sprintf("10.0.0.%d", i)}})
+		}
+		Expect(sourceIPs(failures)).To(HaveLen(maxSourceIPs))
+		Expect(sourceIPs(failures)[0].Address).To(Equal(fmt.Sprintf("10.0.0.%d", 2*maxSourceIPs-1)))
+	})
+})
+
+var _ = Describe("Suspicious logins", func() {
+	ctx := context.Background()
+	key := types.NamespacedName{Name: "ivan", Namespace: "default"}
+
+	It("reports a login following failed logins", func() {
+		resource := &guardv1.Guarduim{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+			Spec: guardv1.GuarduimSpec{Username: "ivan", Threshold: 5, SuccessAfterFailures: 2,
+				EnforcementMode: guardv1.EnforcementModeDisabled},
+		}
+		Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		DeferCleanup(deleteGuarduim, ctx, resource)
+
+		loggedInAt := time.Now().Add(-time.Minute).Truncate(time.Microsecond)
+		reconciler, recorder := newTestReconciler(newTestScanner(ctx, staticLogSource{
+			{Username: "ivan", Decision: auditlog.DecisionDeny, Timestamp: loggedInAt.Add(-2 * time.Second)},
+			{Username: "ivan", Decision: auditlog.DecisionDeny, Timestamp: loggedInAt.Add(-time.Second)},
+			{Username: "ivan", Decision: auditlog.DecisionAllow, Timestamp: loggedInAt, SourceIPs: []string{"203.0.113.7"}},
+		}))
+		// The same login is reported once
+		for range 2 {
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		guarduim := &guardv1.Guarduim{}
+		Expect(k8sClient.Get(ctx, key, guarduim)).To(Succeed())
+		Expect(guarduim.Status.SuspiciousLogin).To(Equal(&guardv1.SuspiciousLogin{
+			Time:         metav1.MicroTime{Time: loggedInAt},
+			FailureCount: 2,
+			SourceIP:     "203.0.113.7",
+		}))
+		Expect(meta.IsStatusConditionTrue(guarduim.Status.Conditions, guardv1.ConditionSuspiciousLogin)).To(BeTrue())
+		Expect(recorder.Events).To(Receive(HavePrefix("Warning SuspiciousLogin")))
+		Expect(recorder.Events).NotTo(Receive())
+	})
+})
+
+var _ = Describe("suspiciousLogin", func() {
+	now := time.Now()
+	at := func(decision auditlog.Decision, ago time.Duration) auditlog.Event {
+		return auditlog.Event{Decision: decision, Timestamp: now.Add(-ago)}
+	}
+
+	It("returns the last login following enough consecutive failures", func() {
+		failures := []auditlog.Event{
+			at(auditlog.DecisionDeny, 6*time.Minute),
+			at(auditlog.DecisionDeny, 5*time.Minute),
+			at(auditlog.DecisionDeny, 3*time.Minute),
+			at(auditlog.DecisionDeny, 2*time.Minute),
+		}
+		logins := []auditlog.Event{
+			at(auditlog.DecisionAllow, 4*time.Minute),
+			at(auditlog.DecisionAllow, time.Minute),
+		}
+		login := suspiciousLogin(failures, logins, 2)
+		Expect(login).NotTo(BeNil())
+		Expect(login.Time.Time).To(Equal(now.Add(-time.Minute)))
+		Expect(login.FailureCount).To(Equal(2))
+
+		Expect(suspiciousLogin(failures, logins, 3)).To(BeNil())
+		Expect(suspiciousLogin(failures, nil, 1)).To(BeNil())
+	})
+})
@@ -0,0 +1,446 @@
+package controller
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	v1 "github.com/SaifRehman/guarduim/api/v1"
+	"github.com/SaifRehman/guarduim/internal/auditlog"
+	"github.com/SaifRehman/guarduim/internal/rules"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Condition reasons of GuarduimPolicy objects
+const (
+	reasonNoStateNamespace = "NoStateNamespace"
+	reasonInvalidSelector  = "InvalidSelector"
+	reasonGenerateFailed   = "GenerateFailed"
+
+	reasonSourceIPBlockingFailed = "SourceIPBlockingFailed"
+	reasonIncidentFailed         = "IncidentFailed"
+)
+
+// GuarduimPolicyReconciler reconciles a GuarduimPolicy object by generating a
+// Guarduim for every matching user with failed logins in the policy window,
+// or matching one of its detection rules. The generated Guarduim is the state
+// of the user: its status records the failures, source addresses and block,
+// and it is deleted along with the policy, or once the user has no recent
+// failures or matching rules and is not blocked.
+type GuarduimPolicyReconciler struct {
+	Client client.Client
+	Scheme *runtime.Scheme
+	Log    logr.Logger
+
+	// Scanners holds the shared scanner of every log source by name.
+	Scanners map[string]*Scanner
+	// DefaultLogSource is used for policies that do not set spec.logSource.
+	DefaultLogSource string
+	// Namespace holds the generated Guarduim objects.
+	Namespace string
+	// Recorder emits Events on GuarduimPolicy objects.
+	Recorder record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=guard.guarduim.com,resources=guarduimpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=guard.guarduim.com,resources=guarduimpolicies/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=guard.guarduim.com,resources=guarduims,verbs=get;list;watch;create;update;patch;delete
+
+func (r *GuarduimPolicyReconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	log := r.Log.WithValues("guarduimpolicy", req.Name)
+
+	// Fetch GuarduimPolicy instance
+	policy := &v1.GuarduimPolicy{}
+	err := r.Client.Get(ctx, req.NamespacedName, policy)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+	policy.Status.ObservedGeneration = policy.Generation
+
+	if r.Namespace == "" {
+		err := fmt.Errorf("the manager has no --state-namespace to generate Guarduim objects in")
+		log.Error(err, "Failed to apply policy")
+		return reconcile.Result{}, r.recordPolicyFailure(ctx, policy, reasonNoStateNamespace, err)
+	}
+
+	// Look up the failures indexed by the shared log scanner
+	sourceName, scanner, err := lookupScanner(r.Scanners, r.DefaultLogSource, policy.Spec.LogSource)
+	if err != nil {
+		log.Error(err, "Failed to select log source")
+		return reconcile.Result{}, r.recordPolicyFailure(ctx, policy, reasonUnknownLogSource, err)
+	}
+	if !scanner.Scanned() {
+		log.Info("Waiting for the first read of the log source", "logSource", sourceName)
+		return reconcile.Result{RequeueAfter: 5 * time.Second}, nil
+	}
+	if err := scanner.Err(); err != nil {
+		log.Error(err, "Failed to list authentication events")
+		_ = r.recordPolicyFailure(ctx, policy, reasonLogSourceFailed, err)
+		return reconcile.Result{}, err
+	}
+
+	selector, err := newUserSelector(policy.Spec.Selector)
+	if err != nil {
+		// Retrying does not help until the policy is fixed
+		log.Error(err, "Invalid policy selector")
+		return reconcile.Result{}, r.recordPolicyFailure(ctx, policy, reasonInvalidSelector, err)
+	}
+	compiledRules, err := compileRules(policy.Spec.Rules)
+	if err != nil {
+		log.Error(err, "Invalid detection rule")
+		return reconcile.Result{}, r.recordPolicyFailure(ctx, policy, reasonInvalidRule, err)
+	}
+	var groups map[string][]string
+	if len(policy.Spec.Selector.Groups) > 0 {
+		if groups, err = groupsByUser(ctx, r.Client); err != nil {
+			log.Error(err, "Failed to list groups")
+			return reconcile.Result{}, err
+		}
+	}
+
+	// Track every matching user with failures inside the window, and the
+	// users still blocked, whose access must be restored once the block ends
+	usernames := map[string]bool{}
+	now := time.Now()
+	var windowStart time.Time
+	if window := policy.Spec.Window; window != nil && window.Duration > 0 {
+		windowStart = now.Add(-window.Duration)
+	}
+	failuresByUser := scanner.FailuresByUser(windowStart)
+	loginsByUser := scanner.LoginsByUser(windowStart)
+	for username, failures := range failuresByUser {
+		if selector.matches(username, groups[username], failures) {
+			usernames[username] = true
+		}
+	}
+
+	// Also track the matching users a detection rule matches, with or without
+	// failed logins
+	matchingRules, err := usersMatchingRules(compiledRules, failuresByUser, loginsByUser,
+		scanner.FailuresByUser(now.Add(-rules.RecentWindow)), rules.Input{Now: now},
+		func(username string, events []auditlog.Event) bool {
+			return selector.matches(username, groups[username], events)
+		})
+	if err != nil {
+		log.Error(err, "Failed to evaluate detection rules")
+	}
+	matchingUsers := map[string]int{}
+	for username, names := range matchingRules {
+		usernames[username] = true
+		for _, name := range names {
+			matchingUsers[name]++
+		}
+	}
+
+	// Whoever fails to log in chooses the username. Leave out those no user
+	// can have, no Guarduim can be generated for them
+	for username := range usernames {
+		if msgs := v1.ValidateUsername(username); len(msgs) > 0 {
+			log.Info("Ignoring invalid username", "username", username, "problems", msgs)
+			delete(usernames, username)
+		}
+	}
+
+	generated, err := r.generatedGuarduims(ctx, policy)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	// Forget the users without recent failures or matching rules. Staleness
+	// is decided from the status of the generated Guarduim, which outlives
+	// the logins the scanner keeps in memory, e.g. across a restart
+	var maxBlockDuration time.Duration
+	if policy.Spec.MaxBlockDuration != nil {
+		maxBlockDuration = policy.Spec.MaxBlockDuration.Duration
+	}
+	for i := range generated {
+		guarduim := &generated[i]
+		if usernames[guarduim.Spec.Username] {
+			continue
+		}
+		if !generatedStale(guarduim, windowStart, now.Add(-maxBlockDuration)) {
+			usernames[guarduim.Spec.Username] = true
+			continue
+		}
+		log.Info("Deleting Guarduim of user without recent failures or matching rules", "username", guarduim.Spec.Username,
+			"lastFailure", guarduim.Status.LastFailure)
+		if err := r.Client.Delete(ctx, guarduim); client.IgnoreNotFound(err) != nil {
+			log.Error(err, "Failed to delete stale Guarduim", "guarduim", guarduim.Name)
+			return reconcile.Result{}, err
+		}
+	}
+
+	// Only generate a Guarduim for new users up to spec.maxTrackedUsers,
+	// those with the most failed logins first
+	tracked := map[string]bool{}
+	for _, guarduim := range generated {
+		tracked[guarduim.Spec.Username] = usernames[guarduim.Spec.Username]
+	}
+	var added []string
+	for username := range usernames {
+		if !tracked[username] {
+			added = append(added, username)
+		}
+	}
+	untracked := 0
+	if limit := policy.Spec.MaxTrackedUsers; limit > 0 && len(usernames) > limit {
+		slices.SortFunc(added, func(a, b string) int {
+			return cmp.Or(cmp.Compare(len(failuresByUser[b]), len(failuresByUser[a])), cmp.Compare(a, b))
+		})
+		keep := max(limit-(len(usernames)-len(added)), 0)
+		for _, username := range added[keep:] {
+			delete(usernames, username)
+		}
+		untracked = len(added) - keep
+		log.Info("Leaving out users beyond spec.maxTrackedUsers", "untracked", untracked)
+	}
+
+	// A user failing does not keep the policy from being applied to the others
+	blocked := 0
+	var generateErr error
+	for username := range usernames {
+		guarduim, err := r.applyPolicy(ctx, policy, username)
+		if err != nil {
+			log.Error(err, "Failed to generate Guarduim", "username", username)
+			if generateErr == nil {
+				generateErr = err
+			}
+			continue
+		}
+		if guarduim.Status.Blocked {
+			blocked++
+		}
+	}
+
+	// Block the address ranges with too many failures, whatever users they tried
+	var ranges []string
+	if blocking := policy.Spec.SourceIPBlocking; blocking != nil {
+		ranges = blockedSourceRanges(failuresByUser, *blocking)
+	}
+	if err := r.applySourceIPBlocking(ctx, policy, ranges); err != nil {
+		log.Error(err, "Failed to block source ranges", "ranges", ranges)
+		_ = r.recordPolicyFailure(ctx, policy, reasonSourceIPBlockingFailed, err)
+		return reconcile.Result{}, err
+	}
+	if added := slices.DeleteFunc(slices.Clone(ranges), func(cidr string) bool {
+		return slices.Contains(policy.Status.BlockedSourceRanges, cidr)
+	}); len(added) > 0 {
+		log.Info("Blocking source ranges", "ranges", added)
+		r.Recorder.Eventf(policy, corev1.EventTypeWarning, eventSourceRangesBlocked,
+			"Blocked source ranges %s", strings.Join(added, ", "))
+	}
+
+	// Raise an incident for the sources trying many usernames
+	if detection := policy.Spec.SprayDetection; detection != nil {
+		for _, source := range detectSprays(failuresByUser, loginsByUser, *detection) {
+			if err := r.raiseIncident(ctx, policy, source); err != nil {
+				log.Error(err, "Failed to raise incident", "sourceIP", source.sourceIP)
+				_ = r.recordPolicyFailure(ctx, policy, reasonIncidentFailed, err)
+				return reconcile.Result{}, err
+			}
+		}
+	}
+
+	// Update the status
+	policy.Status.BlockedSourceRanges = ranges
+	policy.Status.Rules = nil
+	for _, rule := range policy.Spec.Rules {
+		policy.Status.Rules = append(policy.Status.Rules, v1.RuleStatus{Name: rule.Name, MatchingUsers: matchingUsers[rule.Name]})
+	}
+	policy.Status.TrackedUsers = len(usernames)
+	policy.Status.UntrackedUsers = untracked
+	policy.Status.BlockedUsers = blocked
+	message := fmt.Sprintf("Tracking %d users", len(usernames))
+	if untracked > 0 {
+		message += fmt.Sprintf(", leaving out %d beyond spec.maxTrackedUsers", untracked)
+	}
+	if generateErr != nil {
+		if err := r.recordPolicyFailure(ctx, policy, reasonGenerateFailed, generateErr); err != nil {
+			log.Error(err, "Failed to update GuarduimPolicy status")
+		}
+		return reconcile.Result{}, generateErr
+	}
+	meta.SetStatusCondition(&policy.Status.Conditions, metav1.Condition{
+		Type:               v1.ConditionReady,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: policy.Generation,
+		Reason:             reasonChecked,
+		Message:            message,
+	})
+	if err := r.Client.Status().Update(ctx, policy); err != nil {
+		log.Error(err, "Failed to update GuarduimPolicy status")
+		return reconcile.Result{}, err
+	}
+
+	// Requeue after 30 seconds
+	return reconcile.Result{
+		RequeueAfter: 30 * time.Second,
+	}, nil
+}
+
+// recordPolicyFailure sets Ready to false because of err and saves the status
+func (r *GuarduimPolicyReconciler) recordPolicyFailure(ctx context.Context, policy *v1.GuarduimPolicy, reason string, err error) error {
+	meta.SetStatusCondition(&policy.Status.Conditions, metav1.Condition{
+		Type:               v1.ConditionReady,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: policy.Generation,
+		Reason:             reason,
+		Message:            err.Error(),
+	})
+	return r.Client.Status().Update(ctx, policy)
+}
+
+// generatedGuarduims lists the Guarduim objects generated for policy
+func (r *GuarduimPolicyReconciler) generatedGuarduims(ctx context.Context, policy *v1.GuarduimPolicy) ([]v1.Guarduim, error) {
+	guarduims := &v1.GuarduimList{}
+	err := r.Client.List(ctx, guarduims, client.InNamespace(r.Namespace), policyLabels(policy))
+	return guarduims.Items, err
+}
+
+// policyLabels returns the labels of the objects generated for policy
+func policyLabels(policy *v1.GuarduimPolicy) client.MatchingLabels {
+	return client.MatchingLabels{v1.PolicyLabel: string(policy.UID)}
+}
+
+// generatedStale reports whether a generated Guarduim can be deleted: the user
+// is not blocked, its last failure is not after windowStart and its last block,
+// if any, did not end after blockedSince, so that a repeat offender is still
+// blocked for longer
+func generatedStale(guarduim *v1.Guarduim, windowStart, blockedSince time.Time) bool {
+	status := guarduim.Status
+	if status.Blocked {
+		return false
+	}
+	if status.LastFailure != nil && status.LastFailure.After(windowStart) {
+		return false
+	}
+	if status.BlockCount > 0 && status.BlockedUntil != nil && status.BlockedUntil.After(blockedSince) {
+		return false
+	}
+	return true
+}
+
+// applyPolicy creates or updates the Guarduim tracking username for policy
+func (r *GuarduimPolicyReconciler) applyPolicy(ctx context.Context, policy *v1.GuarduimPolicy, username string) (*v1.Guarduim, error) {
+	guarduim := &v1.Guarduim{
+		ObjectMeta: metav1.ObjectMeta{Name: generatedName(policy.Name, username), Namespace: r.Namespace},
+	}
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, guarduim, func() error {
+		// Names are hashed, an existing Guarduim may track another user
+		if guarduim.Spec.Username != "" && guarduim.Spec.Username != username {
+			return fmt.Errorf("guarduim %s tracks user %q, not %q", guarduim.Name, guarduim.Spec.Username, username)
+		}
+		if guarduim.Labels == nil {
+			guarduim.Labels = map[string]string{}
+		}
+		guarduim.Labels[v1.PolicyLabel] = string(policy.UID)
+		guarduim.Spec = v1.GuarduimSpec{
+			Username:              username,
+			Threshold:             policy.Spec.Threshold,
+			LogSource:             policy.Spec.LogSource,
+			Window:                policy.Spec.Window,
+			BlockDuration:         policy.Spec.BlockDuration,
+			MaxBlockDuration:      policy.Spec.MaxBlockDuration,
+			RevokeAuthorizeTokens: policy.Spec.RevokeAuthorizeTokens,
+			SuccessAfterFailures:  policy.Spec.SuccessAfterFailures,
+			LocationDetection:     policy.Spec.LocationDetection,
+			Rules:                 policy.Spec.Rules,
+		}
+		if policy.Spec.Action == v1.PolicyActionAlert {
+			guarduim.Spec.EnforcementMode = v1.EnforcementModeDryRun
+		}
+		return controllerutil.SetControllerReference(policy, guarduim, r.Scheme)
+	})
+	return guarduim, err
+}
+
+// generatedName returns the name of the Guarduim tracking username for a
+// policy. Usernames are hashed, they may contain characters names can not,
+// along with the policy name, which is cut short to keep the name valid.
+func generatedName(policyName, username string) string {
+	sum := sha256.Sum256([]byte(policyName + "\x00" + username))
+	hash := hex.EncodeToString(sum[:])
+	prefix := policyName[:min(len(policyName), validation.DNS1123SubdomainMaxLength-len(hash)-1)]
+	return strings.TrimRight(prefix, ".-") + "-" + hash
+}
+
+// objectName returns prefix followed by suffix. If that is too long for an
+// object name, prefix is cut short and followed by a hash of it, so that
+// the names of policies starting alike still differ.
+func objectName(prefix, suffix string) string {
+	if len(prefix)+len(suffix) <= validation.DNS1123SubdomainMaxLength {
+		return prefix + suffix
+	}
+	sum := sha256.Sum256([]byte(prefix))
+	hash := hex.EncodeToString(sum[:5])
+	prefix = prefix[:validation.DNS1123SubdomainMaxLength-len(suffix)-len(hash)-1]
+	return strings.TrimRight(prefix, ".-") + "-" + hash + suffix
+}
+
+// userSelector is a compiled v1.UserSelector
+type userSelector struct {
+	patterns          []*regexp.Regexp
+	groups            []string
+	identityProviders []string
+}
+
+// newUserSelector compiles selector
+func newUserSelector(selector v1.UserSelector) (*userSelector, error) {
+	s := &userSelector{groups: selector.Groups, identityProviders: selector.IdentityProviders}
+	for _, pattern := range selector.UsernamePatterns {
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid username pattern %q: %w", pattern, err)
+		}
+		s.patterns = append(s.patterns, re)
+	}
+	return s, nil
+}
+
+// matches reports whether a user, with the given groups and failed logins,
+// is selected
+func (s *userSelector) matches(username string, groups []string, failures []auditlog.Event) bool {
+	if len(s.patterns) > 0 && !slices.ContainsFunc(s.patterns, func(re *regexp.Regexp) bool { return re.MatchString(username) }) {
+		return false
+	}
+	if len(s.groups) > 0 && !slices.ContainsFunc(groups, func(group string) bool { return slices.Contains(s.groups, group) }) {
+		return false
+	}
+	if len(s.identityProviders) > 0 && !slices.ContainsFunc(failures, func(event auditlog.Event) bool {
+		return slices.Contains(s.identityProviders, event.IdentityProvider())
+	}) {
+		return false
+	}
+	return true
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *GuarduimPolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1.GuarduimPolicy{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Owns(&networkingv1.NetworkPolicy{}).
+		Owns(&v1.GuarduimIncident{}).
+		Complete(r)
+}
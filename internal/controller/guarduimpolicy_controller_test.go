@@ -0,0 +1,530 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	guardv1 "github.com/SaifRehman/guarduim/api/v1"
+	"github.com/SaifRehman/guarduim/internal/auditlog"
+)
+
+var _ = Describe("GuarduimPolicy Controller", func() {
+	ctx := context.Background()
+
+	var (
+		policy   *guardv1.GuarduimPolicy
+		scanner  *Scanner
+		failedAt time.Time
+	)
+
+	BeforeEach(func() {
+		// Audit timestamps have microsecond precision, like the recorded failures
+		failedAt = time.Now().Add(-time.Minute).Truncate(time.Microsecond)
+		scanner = &Scanner{Source: staticLogSource{
+			{Username: "dev-erin", Decision: auditlog.DecisionDeny, Timestamp: failedAt.Add(-time.Second),
+				SourceIPs: []string{"10.0.0.1"}, RequestURI: "/login/htpasswd"},
+			{Username: "dev-erin", Decision: auditlog.DecisionDeny, Timestamp: failedAt,
+				SourceIPs: []string{"10.0.0.2"}, RequestURI: "/login/htpasswd"},
+			{Username: "frank", Decision: auditlog.DecisionDeny, Timestamp: failedAt, RequestURI: "/login/htpasswd"},
+		}, Retention: time.Hour}
+		scanner.scan(ctx)
+
+		policy = &guardv1.GuarduimPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "developers"},
+			Spec: guardv1.GuarduimPolicySpec{
+				Selector:  guardv1.UserSelector{UsernamePatterns: []string{"dev-.*"}},
+				Threshold: 1,
+				Action:    guardv1.PolicyActionAlert,
+				Window:    &metav1.Duration{Duration: time.Hour},
+			},
+		}
+		Expect(k8sClient.Create(ctx, policy)).To(Succeed())
+		DeferCleanup(func() {
+			generated := &guardv1.GuarduimList{}
+			Expect(k8sClient.List(ctx, generated, client.InNamespace("default"),
+				policyLabels(policy))).To(Succeed())
+			for i := range generated.Items {
+				Expect(deleteGuarduim(ctx, &generated.Items[i])).To(Succeed())
+			}
+			Expect(k8sClient.Delete(ctx, policy)).To(Succeed())
+		})
+	})
+
+	reconcilePolicy := func(namespace string) error {
+		reconciler := &GuarduimPolicyReconciler{
+			Client:           k8sClient,
+			Scheme:           k8sClient.Scheme(),
+			Scanners:         map[string]*Scanner{OAuthServerLogSource: scanner},
+			DefaultLogSource: OAuthServerLogSource,
+			Namespace:        namespace,
+			Recorder:         record.NewFakeRecorder(10),
+		}
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: policy.Name}})
+		return err
+	}
+
+	It("generates a Guarduim for every matching user with failed logins", func() {
+		Expect(reconcilePolicy("default")).To(Succeed())
+
+		guarduims := &guardv1.GuarduimList{}
+		Expect(k8sClient.List(ctx, guarduims, client.InNamespace("default"),
+			policyLabels(policy))).To(Succeed())
+		Expect(guarduims.Items).To(HaveLen(1))
+		generated := guarduims.Items[0]
+		Expect(generated.Name).To(Equal(generatedName(policy.Name, "dev-erin")))
+		Expect(generated.Spec.Username).To(Equal("dev-erin"))
+		Expect(generated.Spec.Threshold).To(Equal(1))
+		Expect(generated.Spec.Window).To(Equal(policy.Spec.Window))
+		Expect(generated.Spec.EnforcementMode).To(Equal(guardv1.EnforcementModeDryRun))
+		Expect(metav1.IsControlledBy(&generated, policy)).To(BeTrue())
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(policy), policy)).To(Succeed())
+		Expect(policy.Status.TrackedUsers).To(Equal(1))
+		Expect(meta.IsStatusConditionTrue(policy.Status.Conditions, guardv1.ConditionReady)).To(BeTrue())
+	})
+
+	It("keeps the generated Guarduims in line with the policy", func() {
+		Expect(reconcilePolicy("default")).To(Succeed())
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(policy), policy)).To(Succeed())
+		policy.Spec.Threshold = 3
+		Expect(k8sClient.Update(ctx, policy)).To(Succeed())
+		Expect(reconcilePolicy("default")).To(Succeed())
+
+		generated := &guardv1.Guarduim{}
+		key := types.NamespacedName{Name: generatedName(policy.Name, "dev-erin"), Namespace: "default"}
+		Expect(k8sClient.Get(ctx, key, generated)).To(Succeed())
+		Expect(generated.Spec.Threshold).To(Equal(3))
+	})
+
+	It("deletes the Guarduims of users without recent failures unless they are blocked", func() {
+		for _, username := range []string{"dev-gone", "dev-blocked"} {
+			Expect(k8sClient.Create(ctx, &guardv1.Guarduim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      generatedName(policy.Name, username),
+					Namespace: "default",
+					Labels:    policyLabels(policy),
+				},
+				Spec: guardv1.GuarduimSpec{Username: username, Threshold: 1},
+			})).To(Succeed())
+		}
+		blocked := &guardv1.Guarduim{}
+		blockedKey := types.NamespacedName{Name: generatedName(policy.Name, "dev-blocked"), Namespace: "default"}
+		Expect(k8sClient.Get(ctx, blockedKey, blocked)).To(Succeed())
+		blocked.Status.Blocked = true
+		Expect(k8sClient.Status().Update(ctx, blocked)).To(Succeed())
+
+		Expect(reconcilePolicy("default")).To(Succeed())
+
+		guarduims := &guardv1.GuarduimList{}
+		Expect(k8sClient.List(ctx, guarduims, client.InNamespace("default"),
+			policyLabels(policy))).To(Succeed())
+		var usernames []string
+		for _, guarduim := range guarduims.Items {
+			usernames = append(usernames, guarduim.Spec.Username)
+		}
+		Expect(usernames).To(ConsistOf("dev-erin", "dev-blocked"))
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(policy), policy)).To(Succeed())
+		Expect(policy.Status.TrackedUsers).To(Equal(2))
+		Expect(policy.Status.BlockedUsers).To(Equal(1))
+	})
+
+	It("keeps the Guarduims of users with failures or blocks recorded in status", func() {
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(policy), policy)).To(Succeed())
+		policy.Spec.MaxBlockDuration = &metav1.Duration{Duration: 24 * time.Hour}
+		Expect(k8sClient.Update(ctx, policy)).To(Succeed())
+
+		// The scanner lost the failures of both users, e.g. on a restart
+		statuses := map[string]guardv1.GuarduimStatus{
+			"dev-failed": {FailureCount: 2, LastFailure: &metav1.Time{Time: failedAt}},
+			"dev-repeat": {BlockCount: 1, BlockedUntil: &metav1.Time{Time: failedAt.Add(-2 * time.Hour)}},
+			"dev-old": {BlockCount: 1, LastFailure: &metav1.Time{Time: failedAt.Add(-2 * time.Hour)},
+				BlockedUntil: &metav1.Time{Time: failedAt.Add(-48 * time.Hour)}},
+		}
+		for username, status := range statuses {
+			guarduim := &guardv1.Guarduim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      generatedName(policy.Name, username),
+					Namespace: "default",
+					Labels:    policyLabels(policy),
+				},
+				Spec: guardv1.GuarduimSpec{Username: username, Threshold: 1},
+			}
+			Expect(k8sClient.Create(ctx, guarduim)).To(Succeed())
+			guarduim.Status = status
+			Expect(k8sClient.Status().Update(ctx, guarduim)).To(Succeed())
+		}
+
+		Expect(reconcilePolicy("default")).To(Succeed())
+
+		guarduims := &guardv1.GuarduimList{}
+		Expect(k8sClient.List(ctx, guarduims, client.InNamespace("default"),
+			policyLabels(policy))).To(Succeed())
+		usernames := map[string]guardv1.GuarduimStatus{}
+		for _, guarduim := range guarduims.Items {
+			usernames[guarduim.Spec.Username] = guarduim.Status
+		}
+		Expect(usernames).To(HaveKey("dev-erin"))
+		Expect(usernames).To(HaveKey("dev-failed"))
+		Expect(usernames["dev-failed"].FailureCount).To(Equal(2))
+		Expect(usernames).To(HaveKey("dev-repeat"))
+		Expect(usernames["dev-repeat"].BlockCount).To(Equal(1))
+		Expect(usernames).NotTo(HaveKey("dev-old"))
+	})
+
+	It("only generates Guarduims for the users with the most failures up to spec.maxTrackedUsers", func() {
+		scanner = newTestScanner(ctx, staticLogSource{
+			{Username: "dev-erin", Decision: auditlog.DecisionDeny, Timestamp: failedAt.Add(-time.Second)},
+			{Username: "dev-erin", Decision: auditlog.DecisionDeny, Timestamp: failedAt},
+			{Username: "dev-ivan", Decision: auditlog.DecisionDeny, Timestamp: failedAt},
+		})
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(policy), policy)).To(Succeed())
+		policy.Spec.MaxTrackedUsers = 1
+		Expect(k8sClient.Update(ctx, policy)).To(Succeed())
+		Expect(reconcilePolicy("default")).To(Succeed())
+
+		guarduims := &guardv1.GuarduimList{}
+		Expect(k8sClient.List(ctx, guarduims, client.InNamespace("default"), policyLabels(policy))).To(Succeed())
+		Expect(guarduims.Items).To(HaveLen(1))
+		Expect(guarduims.Items[0].Spec.Username).To(Equal("dev-erin"))
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(policy), policy)).To(Succeed())
+		Expect(policy.Status.TrackedUsers).To(Equal(1))
+		Expect(policy.Status.UntrackedUsers).To(Equal(1))
+	})
+
+	It("does not take over a Guarduim of another user with the same name", func() {
+		scanner = newTestScanner(ctx, staticLogSource{
+			{Username: "dev-erin", Decision: auditlog.DecisionDeny, Timestamp: failedAt},
+			{Username: "dev-ivan", Decision: auditlog.DecisionDeny, Timestamp: failedAt},
+		})
+		other := &guardv1.Guarduim{
+			ObjectMeta: metav1.ObjectMeta{Name: generatedName(policy.Name, "dev-erin"), Namespace: "default"},
+			Spec:       guardv1.GuarduimSpec{Username: "dev-other", Threshold: 1},
+		}
+		Expect(k8sClient.Create(ctx, other)).To(Succeed())
+		DeferCleanup(deleteGuarduim, ctx, other)
+
+		Expect(reconcilePolicy("default")).To(MatchError(ContainSubstring(`tracks user "dev-other"`)))
+
+		By("still applying the policy to the other users")
+		generated := &guardv1.Guarduim{}
+		key := types.NamespacedName{Name: generatedName(policy.Name, "dev-ivan"), Namespace: "default"}
+		Expect(k8sClient.Get(ctx, key, generated)).To(Succeed())
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(policy), policy)).To(Succeed())
+		Expect(meta.FindStatusCondition(policy.Status.Conditions, guardv1.ConditionReady).Reason).To(Equal(reasonGenerateFailed))
+	})
+
+	It("ignores usernames no user can have", func() {
+		scanner = newTestScanner(ctx, staticLogSource{
+			{Username: "dev-erin", Decision: auditlog.DecisionDeny, Timestamp: failedAt},
+			{Username: "dev-a b", Decision: auditlog.DecisionDeny, Timestamp: failedAt},
+			{Username: "dev-x/y", Decision: auditlog.DecisionDeny, Timestamp: failedAt},
+		})
+		Expect(reconcilePolicy("default")).To(Succeed())
+
+		guarduims := &guardv1.GuarduimList{}
+		Expect(k8sClient.List(ctx, guarduims, client.InNamespace("default"), policyLabels(policy))).To(Succeed())
+		Expect(guarduims.Items).To(HaveLen(1))
+		Expect(guarduims.Items[0].Spec.Username).To(Equal("dev-erin"))
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(policy), policy)).To(Succeed())
+		Expect(policy.Status.TrackedUsers).To(Equal(1))
+		Expect(meta.IsStatusConditionTrue(policy.Status.Conditions, guardv1.ConditionReady)).To(BeTrue())
+	})
+
+	It("blocks the source ranges with too many failures with a NetworkPolicy", func() {
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(policy), policy)).To(Succeed())
+		policy.Spec.SourceIPBlocking = &guardv1.SourceIPBlocking{
+			Threshold:        1,
+			IPv4PrefixLength: 24,
+			Namespace:        "default",
+			PodSelector:      metav1.LabelSelector{MatchLabels: map[string]string{"app": "router"}},
+		}
+		Expect(k8sClient.Update(ctx, policy)).To(Succeed())
+		Expect(reconcilePolicy("default")).To(Succeed())
+
+		networkPolicy := &networkingv1.NetworkPolicy{}
+		key := types.NamespacedName{Name: "guarduim-developers", Namespace: "default"}
+		Expect(k8sClient.Get(ctx, key, networkPolicy)).To(Succeed())
+		Expect(metav1.IsControlledBy(networkPolicy, policy)).To(BeTrue())
+		Expect(networkPolicy.Spec.PodSelector.MatchLabels).To(HaveKeyWithValue("app", "router"))
+		Expect(networkPolicy.Spec.Ingress).To(HaveLen(1))
+		Expect(networkPolicy.Spec.Ingress[0].From[0].IPBlock.Except).To(Equal([]string{"10.0.0.0/24"}))
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(policy), policy)).To(Succeed())
+		Expect(policy.Status.BlockedSourceRanges).To(Equal([]string{"10.0.0.0/24"}))
+
+		By("deleting the NetworkPolicy once source IP blocking is disabled")
+		policy.Spec.SourceIPBlocking = nil
+		Expect(k8sClient.Update(ctx, policy)).To(Succeed())
+		Expect(reconcilePolicy("default")).To(Succeed())
+		Expect(k8sClient.Get(ctx, key, networkPolicy)).To(Satisfy(errors.IsNotFound))
+	})
+
+	It("raises an incident for a source trying many usernames", func() {
+		sprayedAt := failedAt.Add(-time.Second)
+		scanner = &Scanner{Source: staticLogSource{
+			{Username: "alice", Decision: auditlog.DecisionDeny, Timestamp: sprayedAt, SourceIPs: []string{"203.0.113.7"}},
+			{Username: "bob", Decision: auditlog.DecisionDeny, Timestamp: failedAt, SourceIPs: []string{"203.0.113.7"}},
+			{Username: "carol", Decision: auditlog.DecisionDeny, Timestamp: failedAt, SourceIPs: []string{"203.0.113.7"}},
+			{Username: "dave", Decision: auditlog.DecisionDeny, Timestamp: failedAt, SourceIPs: []string{"198.51.100.1"}},
+		}, Retention: time.Hour}
+		scanner.scan(ctx)
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(policy), policy)).To(Succeed())
+		policy.Spec.SprayDetection = &guardv1.SprayDetection{UsernameThreshold: 2}
+		Expect(k8sClient.Update(ctx, policy)).To(Succeed())
+
+		// Failures already counted are not counted again
+		for range 2 {
+			Expect(reconcilePolicy("default")).To(Succeed())
+		}
+
+		incidents := &guardv1.GuarduimIncidentList{}
+		Expect(k8sClient.List(ctx, incidents, policyLabels(policy))).To(Succeed())
+		Expect(incidents.Items).To(HaveLen(1))
+		incident := incidents.Items[0]
+		DeferCleanup(k8sClient.Delete, ctx, &incident)
+		Expect(metav1.IsControlledBy(&incident, policy)).To(BeTrue())
+		Expect(incident.Spec.Type).To(Equal(guardv1.IncidentPasswordSpray))
+		Expect(incident.Spec.SourceIP).To(Equal("203.0.113.7"))
+		Expect(incident.Status.FailureCount).To(Equal(3))
+		Expect(incident.Status.UsernameCount).To(Equal(3))
+		Expect(incident.Status.Usernames).To(Equal([]string{"alice", "bob", "carol"}))
+		Expect(incident.Status.FirstSeen.Time).To(BeTemporally("==", sprayedAt))
+		Expect(incident.Status.LastSeen.Time).To(BeTemporally("==", failedAt))
+	})
+
+	It("tracks the users matching a detection rule and counts them by rule", func() {
+		scanner = &Scanner{Source: staticLogSource{
+			{Username: "dev-erin", Decision: auditlog.DecisionDeny, Timestamp: failedAt},
+			{Username: "dev-gina", Decision: auditlog.DecisionAllow, Timestamp: failedAt.Add(-time.Second)},
+			{Username: "dev-gina", Decision: auditlog.DecisionAllow, Timestamp: failedAt},
+			{Username: "henry", Decision: auditlog.DecisionAllow, Timestamp: failedAt},
+			{Username: "henry", Decision: auditlog.DecisionAllow, Timestamp: failedAt},
+		}, Retention: time.Hour}
+		scanner.scan(ctx)
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(policy), policy)).To(Succeed())
+		policy.Spec.Rules = []guardv1.DetectionRule{
+			{Name: "many-logins", Expression: "logins > 1"},
+			{Name: "burst", Expression: "failures_10m > 5"},
+		}
+		Expect(k8sClient.Update(ctx, policy)).To(Succeed())
+
+		Expect(reconcilePolicy("default")).To(Succeed())
+
+		generated := &guardv1.Guarduim{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: generatedName(policy.Name, "dev-gina"), Namespace: "default"},
+			generated)).To(Succeed())
+		Expect(generated.Spec.Rules).To(Equal(policy.Spec.Rules))
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(policy), policy)).To(Succeed())
+		Expect(policy.Status.TrackedUsers).To(Equal(2))
+		Expect(policy.Status.Rules).To(Equal([]guardv1.RuleStatus{
+			{Name: "many-logins", MatchingUsers: 1},
+			{Name: "burst", MatchingUsers: 0},
+		}))
+	})
+
+	It("reports detection rules that do not compile", func() {
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(policy), policy)).To(Succeed())
+		policy.Spec.Rules = []guardv1.DetectionRule{{Name: "broken", Expression: "failures >"}}
+		Expect(k8sClient.Update(ctx, policy)).To(Succeed())
+
+		Expect(reconcilePolicy("default")).To(Succeed())
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(policy), policy)).To(Succeed())
+		ready := meta.FindStatusCondition(policy.Status.Conditions, guardv1.ConditionReady)
+		Expect(ready).NotTo(BeNil())
+		Expect(ready.Status).To(Equal(metav1.ConditionFalse))
+		Expect(ready.Reason).To(Equal(reasonInvalidRule))
+	})
+
+	It("reports a manager without a state namespace", func() {
+		Expect(reconcilePolicy("")).To(Succeed())
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(policy), policy)).To(Succeed())
+		condition := meta.FindStatusCondition(policy.Status.Conditions, guardv1.ConditionReady)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(reasonNoStateNamespace))
+	})
+
+	It("only alerts on users exceeding the threshold of an alert-only policy", func() {
+		Expect(reconcilePolicy("default")).To(Succeed())
+
+		reconciler, recorder := newTestReconciler(scanner)
+		key := types.NamespacedName{Name: generatedName(policy.Name, "dev-erin"), Namespace: "default"}
+		for range 2 {
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		guarduim := &guardv1.Guarduim{}
+		Expect(k8sClient.Get(ctx, key, guarduim)).To(Succeed())
+		Expect(guarduim.Status.Blocked).To(BeFalse())
+		Expect(guarduim.Status.FailureCount).To(Equal(2))
+		condition := meta.FindStatusCondition(guarduim.Status.Conditions, guardv1.ConditionBlocked)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(reasonDryRun))
+		Expect(meta.IsStatusConditionTrue(guarduim.Status.Conditions, guardv1.ConditionWouldBlock)).To(BeTrue())
+		Expect(guarduim.Status.FirstFailure.Time).To(BeTemporally("==", failedAt.Add(-time.Second).Truncate(time.Second)))
+		Expect(guarduim.Status.LastFailure.Time).To(BeTemporally("==", failedAt.Truncate(time.Second)))
+		Expect(guarduim.Status.SourceIPs).To(Equal([]guardv1.SourceIP{
+			{Address: "10.0.0.2", FailureCount: 1},
+			{Address: "10.0.0.1", FailureCount: 1},
+		}))
+		Expect(recorder.Events).To(Receive(HavePrefix("Warning WouldBlock")))
+		Expect(recorder.Events).NotTo(Receive())
+	})
+})
+
+var _ = Describe("generatedName", func() {
+	It("returns a valid name for every policy and username", func() {
+		long := strings.Repeat("a", 62) + "." + strings.Repeat("b", 190)
+		for _, policyName := range []string{"developers", long} {
+			name := generatedName(policyName, "system:serviceaccount:ci:deployer")
+			Expect(validation.IsDNS1123Subdomain(name)).To(BeEmpty())
+			Expect(name).To(HavePrefix(policyName[:10]))
+		}
+		Expect(generatedName("developers", "alice")).NotTo(Equal(generatedName("developers", "bob")))
+		Expect(generatedName(long, "alice")).NotTo(Equal(generatedName(long+"c", "alice")))
+	})
+})
+
+var _ = Describe("objectName", func() {
+	It("returns a valid name for every policy", func() {
+		long := strings.Repeat("a", 62) + "." + strings.Repeat("b", 190)
+		for _, policyName := range []string{"developers", long} {
+			policy := &guardv1.GuarduimPolicy{ObjectMeta: metav1.ObjectMeta{Name: policyName}}
+			source := &spraySource{sourceIP: "192.0.2.1", loggedIn: sets.New("alice")}
+			for _, name := range []string{incidentName(policy, source), networkPolicyName(policy)} {
+				Expect(validation.IsDNS1123Subdomain(name)).To(BeEmpty())
+			}
+		}
+		Expect(networkPolicyName(&guardv1.GuarduimPolicy{ObjectMeta: metav1.ObjectMeta{Name: "developers"}})).
+			To(Equal("guarduim-developers"))
+		Expect(objectName(long, "-spray")).NotTo(Equal(objectName(long[:252]+"c", "-spray")))
+	})
+})
+
+var _ = Describe("userSelector", func() {
+	failures := []auditlog.Event{{Username: "dev-erin", RequestURI: "/login/htpasswd?then=%2F"}}
+
+	It("selects every user when empty", func() {
+		selector, err := newUserSelector(guardv1.UserSelector{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(selector.matches("anyone", nil, nil)).To(BeTrue())
+	})
+
+	It("requires a match in every non-empty list", func() {
+		selector, err := newUserSelector(guardv1.UserSelector{
+			UsernamePatterns:  []string{"dev-.*", "ops"},
+			Groups:            []string{"developers"},
+			IdentityProviders: []string{"htpasswd"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(selector.matches("dev-erin", []string{"developers"}, failures)).To(BeTrue())
+		Expect(selector.matches("xdev-erin", []string{"developers"}, failures)).To(BeFalse())
+		Expect(selector.matches("dev-erin", []string{"admins"}, failures)).To(BeFalse())
+		Expect(selector.matches("dev-erin", []string{"developers"},
+			[]auditlog.Event{{Username: "dev-erin", RequestURI: "/login/ldap"}})).To(BeFalse())
+	})
+
+	It("rejects invalid username patterns", func() {
+		_, err := newUserSelector(guardv1.UserSelector{UsernamePatterns: []string{"dev-("}})
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("detectSprays", func() {
+	event := func(address, userAgent string) auditlog.Event {
+		return auditlog.Event{Decision: auditlog.DecisionDeny, SourceIPs: []string{address}, UserAgent: userAgent}
+	}
+
+	It("counts the usernames tried by every source", func() {
+		failures := map[string][]auditlog.Event{
+			"alice": {event("203.0.113.7", "curl"), event("198.51.100.1", "curl")},
+			"bob":   {event("203.0.113.7", "python")},
+			"carol": {event("203.0.113.7", "python"), event("203.0.113.7", "python")},
+		}
+		sprays := detectSprays(failures, nil, guardv1.SprayDetection{UsernameThreshold: 2})
+		Expect(sprays).To(HaveLen(1))
+		Expect(sprays[0].sourceIP).To(Equal("203.0.113.7"))
+		Expect(sets.List(sprays[0].usernames)).To(Equal([]string{"alice", "bob", "carol"}))
+		Expect(sprays[0].events).To(HaveLen(4))
+
+		By("telling user agents apart")
+		Expect(detectSprays(failures, nil, guardv1.SprayDetection{UsernameThreshold: 2, ByUserAgent: true})).To(BeEmpty())
+		sprays = detectSprays(failures, nil, guardv1.SprayDetection{UsernameThreshold: 1, ByUserAgent: true})
+		Expect(sprays).To(HaveLen(1))
+		Expect(sprays[0].userAgent).To(Equal("python"))
+	})
+
+	It("reports sources that also logged in as credential stuffing", func() {
+		failures := map[string][]auditlog.Event{
+			"alice": {event("203.0.113.7", "curl")},
+			"bob":   {event("203.0.113.7", "curl")},
+		}
+		logins := map[string][]auditlog.Event{
+			"carol": {event("203.0.113.7", "curl")},
+			"dave":  {event("198.51.100.1", "curl")},
+		}
+		sprays := detectSprays(failures, nil, guardv1.SprayDetection{UsernameThreshold: 1})
+		Expect(sprays).To(HaveLen(1))
+		Expect(sprays[0].incidentType()).To(Equal(guardv1.IncidentPasswordSpray))
+
+		sprays = detectSprays(failures, logins, guardv1.SprayDetection{UsernameThreshold: 1})
+		Expect(sprays).To(HaveLen(1))
+		Expect(sprays[0].incidentType()).To(Equal(guardv1.IncidentCredentialStuffing))
+		Expect(sets.List(sprays[0].loggedIn)).To(Equal([]string{"carol"}))
+	})
+})
+
+var _ = Describe("blockedSourceRanges", func() {
+	event := func(address string) auditlog.Event {
+		return auditlog.Event{Decision: auditlog.DecisionDeny, SourceIPs: []string{address, "10.128.0.5"}, TrustedProxies: 1}
+	}
+
+	It("counts the failures of every user by client address range", func() {
+		failures := map[string][]auditlog.Event{
+			"alice": {event("203.0.113.7"), event("203.0.113.8"), event("2001:db8::1")},
+			"bob":   {event("203.0.113.9"), event("198.51.100.1"), event("2001:db8::2"), event("::ffff:198.51.100.2")},
+		}
+		blocking := guardv1.SourceIPBlocking{Threshold: 1, IPv4PrefixLength: 24}
+		Expect(blockedSourceRanges(failures, blocking)).To(Equal([]string{"198.51.100.0/24", "2001:db8::/64", "203.0.113.0/24"}))
+
+		blocking = guardv1.SourceIPBlocking{Threshold: 2}
+		Expect(blockedSourceRanges(failures, blocking)).To(BeEmpty())
+	})
+})
@@ -0,0 +1,107 @@
+package controller
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-logr/logr"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Enforcement artifacts of versions that blocked users by binding them to an
+// empty ClusterRole instead of revoking their access
+const (
+	legacyBlockedUserClusterRole = "blocked-user"
+	legacyBindingPrefix          = "block-user-"
+)
+
+//+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=clusterroles,resourceNames=blocked-user,verbs=get;delete
+
+// LegacyCleanup deletes the ClusterRoleBindings and the ClusterRole older
+// versions created to block users, which no version removed when a Guarduim
+// was deleted. It runs once when the manager starts.
+type LegacyCleanup struct {
+	Client client.Client
+	// APIReader reads the ClusterRole, which the manager may only get by name
+	// and so can not cache.
+	APIReader client.Reader
+	Log       logr.Logger
+}
+
+// Start implements manager.Runnable.
+func (c *LegacyCleanup) Start(ctx context.Context) error {
+	bindings := &rbacv1.ClusterRoleBindingList{}
+	if err := c.Client.List(ctx, bindings); err != nil {
+		c.Log.Error(err, "Failed to list ClusterRoleBindings")
+		return nil
+	}
+	for i := range bindings.Items {
+		binding := &bindings.Items[i]
+		if !isLegacyBinding(binding) {
+			continue
+		}
+		if err := c.Client.Delete(ctx, binding); client.IgnoreNotFound(err) != nil {
+			c.Log.Error(err, "Failed to delete legacy ClusterRoleBinding", "name", binding.Name)
+			continue
+		}
+		c.Log.Info("Deleted legacy ClusterRoleBinding", "name", binding.Name)
+	}
+
+	clusterRole := &rbacv1.ClusterRole{}
+	if err := c.APIReader.Get(ctx, client.ObjectKey{Name: legacyBlockedUserClusterRole}, clusterRole); err != nil {
+		if !errors.IsNotFound(err) {
+			c.Log.Error(err, "Failed to get legacy ClusterRole", "name", legacyBlockedUserClusterRole)
+		}
+		return nil
+	}
+	if !isLegacyClusterRole(clusterRole) {
+		c.Log.Info("Keeping ClusterRole not created by an older version", "name", clusterRole.Name)
+		return nil
+	}
+	// Only delete the ClusterRole that was checked
+	if err := c.Client.Delete(ctx, clusterRole, client.Preconditions{
+		UID:             &clusterRole.UID,
+		ResourceVersion: &clusterRole.ResourceVersion,
+	}); err != nil {
+		if !errors.IsNotFound(err) {
+			c.Log.Error(err, "Failed to delete legacy ClusterRole", "name", clusterRole.Name)
+		}
+		return nil
+	}
+	c.Log.Info("Deleted legacy ClusterRole", "name", clusterRole.Name)
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable.
+func (c *LegacyCleanup) NeedLeaderElection() bool {
+	return true
+}
+
+// isLegacyBinding reports whether binding blocked a user in older versions
+func isLegacyBinding(binding *rbacv1.ClusterRoleBinding) bool {
+	return strings.HasPrefix(binding.Name, legacyBindingPrefix) &&
+		binding.RoleRef.Kind == "ClusterRole" && binding.RoleRef.Name == legacyBlockedUserClusterRole
+}
+
+// isLegacyClusterRole reports whether clusterRole is the one older versions
+// bound blocked users to, granting nothing
+func isLegacyClusterRole(clusterRole *rbacv1.ClusterRole) bool {
+	return clusterRole.Name == legacyBlockedUserClusterRole && len(clusterRole.Rules) == 0 &&
+		clusterRole.AggregationRule == nil && len(clusterRole.OwnerReferences) == 0
+}
+
+// deleteLegacyBinding deletes the ClusterRoleBinding older versions blocked
+// username with, if there is one
+func deleteLegacyBinding(ctx context.Context, c client.Client, username string) error {
+	binding := &rbacv1.ClusterRoleBinding{}
+	err := c.Get(ctx, client.ObjectKey{Name: legacyBindingPrefix + username}, binding)
+	if err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if !isLegacyBinding(binding) {
+		return nil
+	}
+	return client.IgnoreNotFound(c.Delete(ctx, binding))
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Legacy cleanup", func() {
+	ctx := context.Background()
+
+	It("deletes the bindings and the ClusterRole older versions blocked users with", func() {
+		clusterRole := &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "blocked-user"}}
+		Expect(k8sClient.Create(ctx, clusterRole)).To(Succeed())
+		legacy := &rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "block-user-ivan"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.UserKind, APIGroup: rbacv1.GroupName, Name: "ivan"}},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "blocked-user"},
+		}
+		Expect(k8sClient.Create(ctx, legacy)).To(Succeed())
+		unrelated := &rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "block-user-admins"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.UserKind, APIGroup: rbacv1.GroupName, Name: "ivan"}},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "cluster-admin"},
+		}
+		Expect(k8sClient.Create(ctx, unrelated)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ctx, unrelated)
+
+		Expect((&LegacyCleanup{Client: k8sClient, APIReader: k8sClient}).Start(ctx)).To(Succeed())
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(legacy), legacy)).To(Satisfy(errors.IsNotFound))
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(clusterRole), clusterRole)).To(Satisfy(errors.IsNotFound))
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(unrelated), unrelated)).To(Succeed())
+	})
+
+	It("keeps a blocked-user ClusterRole granting permissions", func() {
+		clusterRole := &rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{Name: "blocked-user"},
+			Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get"}}},
+		}
+		Expect(k8sClient.Create(ctx, clusterRole)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ctx, clusterRole)
+
+		Expect((&LegacyCleanup{Client: k8sClient, APIReader: k8sClient}).Start(ctx)).To(Succeed())
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(clusterRole), clusterRole)).To(Succeed())
+	})
+})
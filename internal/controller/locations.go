@@ -0,0 +1,113 @@
+package controller
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	v1 "github.com/SaifRehman/guarduim/api/v1"
+	"github.com/SaifRehman/guarduim/internal/auditlog"
+	"github.com/SaifRehman/guarduim/internal/geoip"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// maxKnownLocations bounds the countries and ASNs kept in GuarduimStatus.
+	maxKnownLocations = 50
+	// maxLocationAnomalies bounds the anomalies kept in GuarduimStatus.
+	maxLocationAnomalies = 20
+	// minTravelDistance in kilometres ignores hops between nearby locations,
+	// GeoIP locations are not more precise.
+	minTravelDistance = 200
+)
+
+// GeoIP looks up where client addresses are registered.
+type GeoIP interface {
+	Lookup(address string) (geoip.Location, bool)
+}
+
+// checkLocations compares the location of every login among events, oldest
+// first, with where the user logged in from before and returns the anomalies
+// found, oldest first. Only successful logins add to the known countries and
+// ASNs of status and move its last location, failed ones must not teach an
+// attacker's location.
+func checkLocations(status *v1.GuarduimStatus, events []auditlog.Event, db GeoIP, detection v1.LocationDetection) []v1.LocationAnomaly {
+	maxSpeed := detection.MaxSpeed
+	if maxSpeed == 0 {
+		maxSpeed = 1000
+	}
+
+	var anomalies []v1.LocationAnomaly
+	for _, event := range events {
+		status.LocationsCheckedUntil = &metav1.MicroTime{Time: event.Timestamp}
+		address := event.ClientIP()
+		location, ok := db.Lookup(address)
+		if !ok {
+			continue
+		}
+
+		login := v1.LoginLocation{
+			Time:     metav1.MicroTime{Time: event.Timestamp},
+			SourceIP: address,
+			Country:  location.Country,
+			ASN:      int64(location.ASN),
+		}
+		anomaly := func(anomalyType v1.LocationAnomalyType, message string) {
+			anomalies = append(anomalies, v1.LocationAnomaly{
+				Type:          anomalyType,
+				LoginLocation: login,
+				Succeeded:     event.Allowed(),
+				Message:       message,
+			})
+		}
+
+		if login.Country != "" && len(status.KnownCountries) > 0 && !slices.Contains(status.KnownCountries, login.Country) {
+			anomaly(v1.LocationNewCountry, fmt.Sprintf("first login from country %s", login.Country))
+		}
+		if login.ASN != 0 && len(status.KnownASNs) > 0 && !slices.Contains(status.KnownASNs, login.ASN) {
+			anomaly(v1.LocationNewASN, fmt.Sprintf("first login from AS%d %s", login.ASN, location.Organization))
+		}
+		if last := status.LastLocation; last != nil && location.HasCoordinates {
+			if previous, ok := db.Lookup(last.SourceIP); ok && previous.HasCoordinates {
+				distance := geoip.Distance(previous, location)
+				elapsed := event.Timestamp.Sub(last.Time.Time)
+				if distance > minTravelDistance && distance > float64(maxSpeed)*elapsed.Hours() {
+					anomaly(v1.LocationImpossibleTravel, fmt.Sprintf("%.0f km from the login from %s %s earlier",
+						distance, last.SourceIP, elapsed.Round(time.Second)))
+				}
+			}
+		}
+
+		if event.Allowed() {
+			if login.Country != "" && !slices.Contains(status.KnownCountries, login.Country) &&
+				len(status.KnownCountries) < maxKnownLocations {
+				status.KnownCountries = append(status.KnownCountries, login.Country)
+			}
+			if login.ASN != 0 && !slices.Contains(status.KnownASNs, login.ASN) && len(status.KnownASNs) < maxKnownLocations {
+				status.KnownASNs = append(status.KnownASNs, login.ASN)
+			}
+			status.LastLocation = &login
+		}
+	}
+	return anomalies
+}
+
+// recordLocationAnomalies adds anomalies, oldest first, to the most recent
+// first LocationAnomalies of status, keeping at most maxLocationAnomalies
+func recordLocationAnomalies(status *v1.GuarduimStatus, anomalies []v1.LocationAnomaly) {
+	for _, anomaly := range anomalies {
+		status.LocationAnomalies = slices.Insert(status.LocationAnomalies, 0, anomaly)
+	}
+	if len(status.LocationAnomalies) > maxLocationAnomalies {
+		status.LocationAnomalies = status.LocationAnomalies[:maxLocationAnomalies]
+	}
+}
+
+// recentLocationAnomaly returns the last location anomaly of status if it is
+// later than windowStart
+func recentLocationAnomaly(status *v1.GuarduimStatus, windowStart time.Time) *v1.LocationAnomaly {
+	if len(status.LocationAnomalies) == 0 || !status.LocationAnomalies[0].Time.After(windowStart) {
+		return nil
+	}
+	return &status.LocationAnomalies[0]
+}
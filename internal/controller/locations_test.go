@@ -0,0 +1,176 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	guardv1 "github.com/SaifRehman/guarduim/api/v1"
+	"github.com/SaifRehman/guarduim/internal/auditlog"
+	"github.com/SaifRehman/guarduim/internal/geoip"
+)
+
+// staticGeoIP locates the addresses it holds
+type staticGeoIP map[string]geoip.Location
+
+func (s staticGeoIP) Lookup(address string) (geoip.Location, bool) {
+	location, ok := s[address]
+	return location, ok
+}
+
+var locations = staticGeoIP{
+	"192.0.2.1":    {Country: "FR", ASN: 3215, HasCoordinates: true, Latitude: 48.8566, Longitude: 2.3522},
+	"192.0.2.2":    {Country: "FR", ASN: 12322, HasCoordinates: true, Latitude: 48.8566, Longitude: 2.3522},
+	"198.51.100.1": {Country: "US", ASN: 7018, HasCoordinates: true, Latitude: 40.7128, Longitude: -74.0060},
+}
+
+var _ = Describe("checkLocations", func() {
+	now := time.Now()
+	login := func(decision auditlog.Decision, address string, ago time.Duration) auditlog.Event {
+		return auditlog.Event{Decision: decision, SourceIPs: []string{address}, Timestamp: now.Add(-ago)}
+	}
+
+	types := func(anomalies []guardv1.LocationAnomaly) []guardv1.LocationAnomalyType {
+		var types []guardv1.LocationAnomalyType
+		for _, anomaly := range anomalies {
+			types = append(types, anomaly.Type)
+		}
+		return types
+	}
+
+	It("learns where the user logs in from", func() {
+		status := &guardv1.GuarduimStatus{}
+		anomalies := checkLocations(status, []auditlog.Event{
+			login(auditlog.DecisionDeny, "198.51.100.1", 10*time.Hour),
+			login(auditlog.DecisionAllow, "192.0.2.1", 2*time.Hour),
+			login(auditlog.DecisionAllow, "192.0.2.2", time.Hour),
+			login(auditlog.DecisionAllow, "203.0.113.1", 0),
+		}, locations, guardv1.LocationDetection{})
+		Expect(status.KnownCountries).To(Equal([]string{"FR"}))
+		Expect(status.KnownASNs).To(Equal([]int64{3215, 12322}))
+		Expect(status.LastLocation.SourceIP).To(Equal("192.0.2.2"))
+		Expect(types(anomalies)).To(Equal([]guardv1.LocationAnomalyType{guardv1.LocationNewASN}))
+		Expect(anomalies[0].Succeeded).To(BeTrue())
+
+		By("not learning from failed logins")
+		anomalies = checkLocations(status, []auditlog.Event{
+			login(auditlog.DecisionDeny, "198.51.100.1", 0),
+		}, locations, guardv1.LocationDetection{MaxSpeed: 10000})
+		Expect(types(anomalies)).To(Equal([]guardv1.LocationAnomalyType{guardv1.LocationNewCountry, guardv1.LocationNewASN}))
+		Expect(status.KnownCountries).To(Equal([]string{"FR"}))
+		Expect(status.LastLocation.SourceIP).To(Equal("192.0.2.2"))
+		Expect(status.LocationsCheckedUntil.Time).To(Equal(now))
+	})
+
+	It("measures travel from the last successful login", func() {
+		status := &guardv1.GuarduimStatus{}
+		checkLocations(status, []auditlog.Event{
+			login(auditlog.DecisionAllow, "192.0.2.1", 3*time.Hour),
+			login(auditlog.DecisionDeny, "198.51.100.1", 2*time.Hour),
+		}, locations, guardv1.LocationDetection{})
+		Expect(status.LastLocation.SourceIP).To(Equal("192.0.2.1"))
+
+		// Paris to New York is too far in two hours, whatever failed in between
+		anomalies := checkLocations(status, []auditlog.Event{
+			login(auditlog.DecisionAllow, "198.51.100.1", time.Hour),
+		}, locations, guardv1.LocationDetection{})
+		Expect(types(anomalies)).To(ContainElement(guardv1.LocationImpossibleTravel))
+	})
+
+	It("reports logins too far apart to travel between", func() {
+		events := []auditlog.Event{
+			login(auditlog.DecisionAllow, "198.51.100.1", 2*time.Hour),
+			login(auditlog.DecisionDeny, "192.0.2.1", time.Hour),
+		}
+		anomalies := checkLocations(&guardv1.GuarduimStatus{}, events, locations, guardv1.LocationDetection{})
+		Expect(types(anomalies)).To(ContainElement(guardv1.LocationImpossibleTravel))
+
+		By("allowing the hop at a higher speed")
+		anomalies = checkLocations(&guardv1.GuarduimStatus{}, events, locations, guardv1.LocationDetection{MaxSpeed: 6000})
+		Expect(types(anomalies)).NotTo(ContainElement(guardv1.LocationImpossibleTravel))
+	})
+})
+
+var _ = Describe("Location detection", func() {
+	ctx := context.Background()
+	key := types.NamespacedName{Name: "judy", Namespace: "default"}
+
+	It("blocks the user after a login from an unusual location", func() {
+		resource := &guardv1.Guarduim{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+			Spec: guardv1.GuarduimSpec{Username: "judy", Threshold: 5,
+				LocationDetection: &guardv1.LocationDetection{Block: true}},
+		}
+		Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		DeferCleanup(deleteGuarduim, ctx, resource)
+
+		loggedInAt := time.Now().Add(-time.Minute).Truncate(time.Microsecond)
+		reconciler, recorder := newTestReconciler(newTestScanner(ctx, staticLogSource{
+			{Username: "judy", Decision: auditlog.DecisionAllow, Timestamp: loggedInAt.Add(-time.Second), SourceIPs: []string{"192.0.2.1"}},
+			{Username: "judy", Decision: auditlog.DecisionAllow, Timestamp: loggedInAt, SourceIPs: []string{"198.51.100.1"}},
+		}))
+		reconciler.GeoIP = locations
+		// The same logins are checked once
+		for range 2 {
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		guarduim := &guardv1.Guarduim{}
+		Expect(k8sClient.Get(ctx, key, guarduim)).To(Succeed())
+		Expect(guarduim.Status.LocationAnomalies).To(HaveLen(3))
+		Expect(guarduim.Status.LocationAnomalies[0].Time.Time).To(BeTemporally("==", loggedInAt))
+		Expect(guarduim.Status.LastLocation.SourceIP).To(Equal("198.51.100.1"))
+		Expect(guarduim.Status.Blocked).To(BeTrue())
+		Expect(meta.FindStatusCondition(guarduim.Status.Conditions, guardv1.ConditionBlocked).Reason).To(Equal(reasonUnusualLocation))
+		Expect(meta.IsStatusConditionTrue(guarduim.Status.Conditions, guardv1.ConditionLocationAnomaly)).To(BeTrue())
+		for range 3 {
+			Expect(recorder.Events).To(Receive(Or(HavePrefix("Warning NewCountry"), HavePrefix("Warning NewASN"),
+				HavePrefix("Warning ImpossibleTravel"))))
+		}
+		Expect(recorder.Events).To(Receive(HavePrefix("Warning Blocked")))
+	})
+
+	It("reports a manager without a GeoIP database", func() {
+		resource := &guardv1.Guarduim{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+			Spec: guardv1.GuarduimSpec{Username: "judy", Threshold: 5,
+				LocationDetection: &guardv1.LocationDetection{}},
+		}
+		Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		DeferCleanup(deleteGuarduim, ctx, resource)
+
+		reconciler, _ := newTestReconciler(newTestScanner(ctx, staticLogSource{}))
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		guarduim := &guardv1.Guarduim{}
+		Expect(k8sClient.Get(ctx, key, guarduim)).To(Succeed())
+		condition := meta.FindStatusCondition(guarduim.Status.Conditions, guardv1.ConditionLocationAnomaly)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionUnknown))
+		Expect(condition.Reason).To(Equal(reasonNoGeoIPDatabase))
+	})
+})
@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"context"
+	"errors"
+
+	"github.com/SaifRehman/guarduim/internal/auditlog"
+	"github.com/go-logr/logr"
+)
+
+// OAuthServerLogSource is the name of the OpenShift OAuth server audit log source.
+const OAuthServerLogSource = "oauth-server"
+
+// LogSource lists authentication events from an identity provider.
+type LogSource interface {
+	// List returns the events recorded after since, together with the cursor
+	// to pass on the next call. A nil cursor lists every available event.
+	List(ctx context.Context, since Cursor) ([]auditlog.Event, Cursor, error)
+}
+
+// Cursor records how far each stream of a LogSource has been read, keyed by
+// stream name (the node name for node audit logs).
+type Cursor map[string]auditlog.Position
+
+// OAuthServerSource lists the login attempts recorded in the OpenShift OAuth
+// server audit log on every master node.
+type OAuthServerSource struct {
+	Reader *auditlog.NodeLogReader
+	// TrustedProxies is the number of proxies in front of the OAuth server
+	// trusted to forward the client address, see auditlog.Event.ClientIP.
+	TrustedProxies int
+	Log            logr.Logger
+}
+
+// List implements LogSource. Only the part of each node's log written after
+// the cursor is fetched. A node whose log can not be read, e.g. while it
+// reboots during an upgrade, is skipped and read from its previous cursor on
+// the next call; List only fails when no node can be read.
+func (s *OAuthServerSource) List(ctx context.Context, since Cursor) ([]auditlog.Event, Cursor, error) {
+	nodes, err := s.Reader.Nodes(ctx)
+	if err != nil {
+		return nil, since, err
+	}
+
+	var events []auditlog.Event
+	var errs []error
+	next := Cursor{}
+	for _, node := range nodes {
+		nodeEvents, pos, err := s.Reader.ReadNode(ctx, node, since[node])
+		if err != nil {
+			s.Log.Error(err, "Failed to read node audit log, skipping the node", "node", node)
+			errs = append(errs, err)
+			if pos, ok := since[node]; ok {
+				next[node] = pos
+			}
+			continue
+		}
+		for i := range nodeEvents {
+			nodeEvents[i].TrustedProxies = s.TrustedProxies
+		}
+		events = append(events, nodeEvents...)
+		next[node] = pos
+	}
+
+	if len(nodes) > 0 && len(errs) == len(nodes) {
+		return nil, since, errors.Join(errs...)
+	}
+	return events, next, nil
+}
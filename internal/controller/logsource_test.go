@@ -0,0 +1,87 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+
+	"github.com/SaifRehman/guarduim/internal/auditlog"
+)
+
+var _ = Describe("OAuthServerSource", func() {
+	const denyLine = `{"kind":"Event","apiVersion":"audit.k8s.io/v1","auditID":"6f0c1b5e","requestURI":"/oauth/token",` +
+		`"sourceIPs":["10.0.0.15"],"requestReceivedTimestamp":"2025-01-20T10:15:30.123456Z",` +
+		`"annotations":{"authentication.openshift.io/decision":"deny","authentication.openshift.io/username":"alice"}}`
+
+	var source *OAuthServerSource
+
+	BeforeEach(func() {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/api/v1/nodes", func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(corev1.NodeList{
+				TypeMeta: metav1.TypeMeta{Kind: "NodeList", APIVersion: "v1"},
+				Items: []corev1.Node{
+					{ObjectMeta: metav1.ObjectMeta{Name: "master-0"}},
+					{ObjectMeta: metav1.ObjectMeta{Name: "master-1"}},
+				},
+			})
+		})
+		mux.HandleFunc("/api/v1/nodes/master-0/proxy/logs/oauth-server/audit.log", func(w http.ResponseWriter, r *http.Request) {
+			http.ServeContent(w, r, "audit.log", time.Time{}, strings.NewReader(denyLine+"\n"))
+		})
+		mux.HandleFunc("/api/v1/nodes/master-1/proxy/logs/oauth-server/audit.log", func(w http.ResponseWriter, _ *http.Request) {
+			http.Error(w, "node is rebooting", http.StatusServiceUnavailable)
+		})
+		server := httptest.NewServer(mux)
+		DeferCleanup(server.Close)
+
+		reader, err := auditlog.NewNodeLogReader(&rest.Config{Host: server.URL})
+		Expect(err).NotTo(HaveOccurred())
+		source = &OAuthServerSource{Reader: reader}
+	})
+
+	It("skips a node that can not be read, keeping its cursor", func() {
+		since := Cursor{"master-1": {Offset: 42, AuditID: "8d2e4a71"}}
+		events, next, err := source.List(context.Background(), since)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(events).To(HaveLen(1))
+		Expect(events[0].Username).To(Equal("alice"))
+		Expect(next["master-0"].AuditID).To(Equal("6f0c1b5e"))
+		Expect(next["master-1"]).To(Equal(since["master-1"]))
+	})
+
+	It("fails when no node can be read", func() {
+		source.Reader.Path = "kube-apiserver/audit.log"
+
+		since := Cursor{"master-0": {Offset: 42}}
+		_, next, err := source.List(context.Background(), since)
+		Expect(err).To(HaveOccurred())
+		Expect(next).To(Equal(since))
+	})
+})
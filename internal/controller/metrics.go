@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"context"
+	"math"
+
+	v1 "github.com/SaifRehman/guarduim/api/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Actions counted by blockActionsTotal
+const (
+	actionBlock        = "block"
+	actionUnblock      = "unblock"
+	actionRevokeTokens = "revoke_tokens"
+)
+
+// otherUsername is the username label of the users no Guarduim of their own
+// watches, only one generated for a policy
+const otherUsername = "other"
+
+// usernameLabel returns the username label of the user of guarduim. Users of
+// generated Guarduims share otherUsername, whoever fails to log in chooses
+// their usernames and their series would never be removed
+func usernameLabel(guarduim *v1.Guarduim) string {
+	if _, generated := guarduim.Labels[v1.PolicyLabel]; generated {
+		return otherUsername
+	}
+	return guarduim.Spec.Username
+}
+
+var (
+	authFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "guarduim_auth_failures_total",
+		Help: "Failed logins read from the log sources, by username, or other for the users without a Guarduim of their own.",
+	}, []string{"username"})
+
+	blockActionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "guarduim_block_actions_total",
+		Help: "Enforcement actions taken, by action: block, unblock or revoke_tokens (one per token).",
+	}, []string{"action"})
+
+	suspiciousLoginsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "guarduim_suspicious_logins_total",
+		Help: "Successful logins following failed logins of the same user, by username, or other for the users without a Guarduim of their own.",
+	}, []string{"username"})
+
+	incidentsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "guarduim_incidents_total",
+		Help: "Incidents raised for sources trying many usernames, by type: PasswordSpray or CredentialStuffing.",
+	}, []string{"type"})
+
+	locationAnomaliesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "guarduim_location_anomalies_total",
+		Help: "Logins from unusual locations, by type: NewCountry, NewASN or ImpossibleTravel.",
+	}, []string{"type"})
+
+	ruleMatchesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "guarduim_rule_matches_total",
+		Help: "Users newly matching a custom detection rule, by rule name.",
+	}, []string{"rule"})
+
+	logScanDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "guarduim_log_scan_duration_seconds",
+		Help:    "Time taken to read a log source, by log source.",
+		Buckets: prometheus.ExponentialBuckets(0.05, 2, 10),
+	}, []string{"log_source"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(authFailuresTotal, blockActionsTotal, suspiciousLoginsTotal, incidentsTotal,
+		locationAnomaliesTotal, ruleMatchesTotal, logScanDuration)
+}
+
+// newBlockedUsersGauge returns the guarduim_blocked_users gauge, counted from
+// the Guarduim objects in reader on every scrape
+func newBlockedUsersGauge(reader client.Reader) prometheus.GaugeFunc {
+	return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "guarduim_blocked_users",
+		Help: "Number of distinct users currently blocked.",
+	}, func() float64 {
+		guarduims := &v1.GuarduimList{}
+		if err := reader.List(context.Background(), guarduims); err != nil {
+			return math.NaN()
+		}
+
+		blocked := map[string]bool{}
+		for _, guarduim := range guarduims.Items {
+			if guarduim.Status.Blocked {
+				blocked[guarduim.Spec.Username] = true
+			}
+		}
+		return float64(len(blocked))
+	})
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	guardv1 "github.com/SaifRehman/guarduim/api/v1"
+	"github.com/SaifRehman/guarduim/internal/auditlog"
+)
+
+var _ = Describe("Metrics", func() {
+	ctx := context.Background()
+
+	It("counts the failed logins read by the scanner per username with a Guarduim of its own", func() {
+		guarduim := &guardv1.Guarduim{
+			ObjectMeta: metav1.ObjectMeta{Name: "mallory", Namespace: "default"},
+			Spec:       guardv1.GuarduimSpec{Username: "mallory", Threshold: 5},
+		}
+		Expect(k8sClient.Create(ctx, guarduim)).To(Succeed())
+		DeferCleanup(deleteGuarduim, ctx, guarduim)
+		generated := &guardv1.Guarduim{
+			ObjectMeta: metav1.ObjectMeta{Name: "sprayed", Namespace: "default",
+				Labels: map[string]string{guardv1.PolicyLabel: "developers"}},
+			Spec: guardv1.GuarduimSpec{Username: "sprayed", Threshold: 5},
+		}
+		Expect(k8sClient.Create(ctx, generated)).To(Succeed())
+		DeferCleanup(deleteGuarduim, ctx, generated)
+
+		before := testutil.ToFloat64(authFailuresTotal.WithLabelValues("mallory"))
+		beforeOther := testutil.ToFloat64(authFailuresTotal.WithLabelValues(otherUsername))
+
+		scanner := &Scanner{Source: staticLogSource{
+			{Username: "mallory", Decision: auditlog.DecisionDeny, Timestamp: time.Now()},
+			{Username: "mallory", Decision: auditlog.DecisionAllow, Timestamp: time.Now()},
+			{Username: "mallory", Decision: auditlog.DecisionDeny, Timestamp: time.Now()},
+			{Username: "unwatched", Decision: auditlog.DecisionDeny, Timestamp: time.Now()},
+			{Username: "sprayed", Decision: auditlog.DecisionDeny, Timestamp: time.Now()},
+		}, Retention: time.Hour, Client: k8sClient}
+		scanner.scan(ctx)
+
+		Expect(testutil.ToFloat64(authFailuresTotal.WithLabelValues("mallory")) - before).To(Equal(2.0))
+		Expect(testutil.ToFloat64(authFailuresTotal.WithLabelValues(otherUsername)) - beforeOther).To(Equal(2.0))
+		Expect(authFailuresTotal.DeleteLabelValues("unwatched")).To(BeFalse())
+		Expect(authFailuresTotal.DeleteLabelValues("sprayed")).To(BeFalse())
+	})
+
+	It("counts each blocked user once", func() {
+		for name, status := range map[string]struct {
+			username string
+			blocked  bool
+		}{
+			"erin-a":  {"erin", true},
+			"erin-b":  {"erin", true},
+			"frank":   {"frank", true},
+			"grace-a": {"grace", false},
+		} {
+			guarduim := &guardv1.Guarduim{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+				Spec:       guardv1.GuarduimSpec{Username: status.username, Threshold: 1},
+			}
+			Expect(k8sClient.Create(ctx, guarduim)).To(Succeed())
+			DeferCleanup(k8sClient.Delete, ctx, guarduim)
+			guarduim.Status.Blocked = status.blocked
+			Expect(k8sClient.Status().Update(ctx, guarduim)).To(Succeed())
+		}
+
+		Expect(testutil.ToFloat64(newBlockedUsersGauge(k8sClient))).To(Equal(2.0))
+	})
+})
@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/SaifRehman/guarduim/api/v1"
+	"github.com/SaifRehman/guarduim/internal/auditlog"
+	"github.com/SaifRehman/guarduim/internal/rules"
+)
+
+// compileRules compiles the detection rules of a spec, returning those that
+// compile along with the errors of the others
+func compileRules(specRules []v1.DetectionRule) ([]*rules.Rule, error) {
+	var compiled []*rules.Rule
+	var errs []error
+	for _, rule := range specRules {
+		c, err := rules.Compile(rule.Name, rule.Expression)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid rule %q: %w", rule.Name, err))
+			continue
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled, errors.Join(errs...)
+}
+
+// matchRules returns the names of the detection rules of a spec matching
+// input, along with the errors of the rules that do not compile or evaluate
+func matchRules(specRules []v1.DetectionRule, input rules.Input) ([]string, error) {
+	compiled, compileErr := compileRules(specRules)
+	matched, err := rules.Matching(compiled, input)
+	return matched, errors.Join(compileErr, err)
+}
+
+// countRuleMatch counts the rule named name newly matching the user of status
+func countRuleMatch(status *v1.GuarduimStatus, name string, now time.Time) {
+	i := slices.IndexFunc(status.RuleMatches, func(matches v1.RuleMatches) bool { return matches.Name == name })
+	if i < 0 {
+		status.RuleMatches = append(status.RuleMatches, v1.RuleMatches{Name: name})
+		i = len(status.RuleMatches) - 1
+	}
+	status.RuleMatches[i].Matches++
+	status.RuleMatches[i].LastMatch = &metav1.Time{Time: now}
+}
+
+// usersMatchingRules evaluates compiled against the failed and successful
+// logins of every user selected by keep, and the recent failures of the user,
+// and returns the rules matching each user, leaving out the users no rule
+// matches, along with the evaluation errors
+func usersMatchingRules(compiled []*rules.Rule, failures, logins, recent map[string][]auditlog.Event,
+	input rules.Input, keep func(username string, events []auditlog.Event) bool) (map[string][]string, error) {
+	matching := map[string][]string{}
+	if len(compiled) == 0 {
+		return matching, nil
+	}
+
+	var errs []error
+	for _, index := range []map[string][]auditlog.Event{failures, logins} {
+		for username := range index {
+			if _, ok := matching[username]; ok {
+				continue
+			}
+			if !keep(username, slices.Concat(failures[username], logins[username])) {
+				matching[username] = nil
+				continue
+			}
+
+			input.Username, input.Failures, input.Logins = username, failures[username], logins[username]
+			input.RecentFailures = recent[username]
+			names, err := rules.Matching(compiled, input)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			matching[username] = names
+		}
+	}
+
+	for username, names := range matching {
+		if len(names) == 0 {
+			delete(matching, username)
+		}
+	}
+	return matching, errors.Join(errs...)
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	guardv1 "github.com/SaifRehman/guarduim/api/v1"
+	"github.com/SaifRehman/guarduim/internal/auditlog"
+	"github.com/SaifRehman/guarduim/internal/rules"
+)
+
+var _ = Describe("Detection rules", func() {
+	ctx := context.Background()
+	key := types.NamespacedName{Name: "judy", Namespace: "default"}
+
+	var (
+		scanner  *Scanner
+		recorder *record.FakeRecorder
+	)
+
+	BeforeEach(func() {
+		failedAt := time.Now().Add(-time.Minute).Truncate(time.Microsecond)
+		scanner = newTestScanner(ctx, staticLogSource{
+			{Username: "judy", Decision: auditlog.DecisionDeny, Timestamp: failedAt.Add(-2 * time.Second), SourceIPs: []string{"192.0.2.1"}},
+			{Username: "judy", Decision: auditlog.DecisionDeny, Timestamp: failedAt.Add(-time.Second), SourceIPs: []string{"192.0.2.2"}},
+			{Username: "judy", Decision: auditlog.DecisionDeny, Timestamp: failedAt, SourceIPs: []string{"192.0.2.3"}},
+		})
+	})
+
+	reconcileGuarduim := func(rules ...guardv1.DetectionRule) *guardv1.Guarduim {
+		resource := &guardv1.Guarduim{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+			Spec: guardv1.GuarduimSpec{Username: "judy", Threshold: 5, Rules: rules,
+				EnforcementMode: guardv1.EnforcementModeDryRun},
+		}
+		Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		DeferCleanup(deleteGuarduim, ctx, resource)
+
+		var reconciler *GuarduimReconciler
+		reconciler, recorder = newTestReconciler(scanner)
+		// A rule matching again is reported once
+		for range 2 {
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		guarduim := &guardv1.Guarduim{}
+		Expect(k8sClient.Get(ctx, key, guarduim)).To(Succeed())
+		return guarduim
+	}
+
+	It("would block a user below the threshold matching a rule", func() {
+		guarduim := reconcileGuarduim(
+			guardv1.DetectionRule{Name: "many-ips", Expression: "failures_10m > 2 && distinct_ips > 2"},
+			guardv1.DetectionRule{Name: "logged-in", Expression: "logins > 0"},
+		)
+
+		Expect(guarduim.Status.FailureCount).To(Equal(3))
+		Expect(guarduim.Status.MatchedRules).To(Equal([]string{"many-ips"}))
+		Expect(guarduim.Status.RuleMatches).To(HaveLen(1))
+		Expect(guarduim.Status.RuleMatches[0].Name).To(Equal("many-ips"))
+		Expect(guarduim.Status.RuleMatches[0].Matches).To(Equal(1))
+		matched := meta.FindStatusCondition(guarduim.Status.Conditions, guardv1.ConditionRuleMatched)
+		Expect(matched).NotTo(BeNil())
+		Expect(matched.Status).To(Equal(metav1.ConditionTrue))
+		wouldBlock := meta.FindStatusCondition(guarduim.Status.Conditions, guardv1.ConditionWouldBlock)
+		Expect(wouldBlock).NotTo(BeNil())
+		Expect(wouldBlock.Status).To(Equal(metav1.ConditionTrue))
+		Expect(wouldBlock.Message).To(ContainSubstring(`matching rule "many-ips"`))
+
+		Expect(recorder.Events).To(Receive(HavePrefix("Warning RuleMatched")))
+		Expect(recorder.Events).To(Receive(HavePrefix("Warning WouldBlock")))
+		Expect(recorder.Events).NotTo(Receive())
+	})
+
+	It("does not block a user without failed logins matching a rule", func() {
+		scanner = newTestScanner(ctx, staticLogSource{
+			{Username: "judy", Decision: auditlog.DecisionAllow, Timestamp: time.Now().Add(-time.Minute), SourceIPs: []string{"192.0.2.1"}},
+		})
+		guarduim := reconcileGuarduim(guardv1.DetectionRule{Name: "anyone", Expression: "true"})
+
+		Expect(guarduim.Status.MatchedRules).To(Equal([]string{"anyone"}))
+		Expect(meta.IsStatusConditionTrue(guarduim.Status.Conditions, guardv1.ConditionRuleMatched)).To(BeTrue())
+		Expect(meta.IsStatusConditionTrue(guarduim.Status.Conditions, guardv1.ConditionWouldBlock)).To(BeFalse())
+		Expect(recorder.Events).To(Receive(HavePrefix("Warning RuleMatched")))
+		Expect(recorder.Events).NotTo(Receive(HavePrefix("Warning WouldBlock")))
+	})
+
+	It("still checks the user when a rule does not compile", func() {
+		guarduim := reconcileGuarduim(guardv1.DetectionRule{Name: "broken", Expression: "failures >"})
+
+		Expect(guarduim.Status.MatchedRules).To(BeEmpty())
+		matched := meta.FindStatusCondition(guarduim.Status.Conditions, guardv1.ConditionRuleMatched)
+		Expect(matched).NotTo(BeNil())
+		Expect(matched.Status).To(Equal(metav1.ConditionUnknown))
+		Expect(matched.Reason).To(Equal(reasonInvalidRule))
+		Expect(meta.IsStatusConditionTrue(guarduim.Status.Conditions, guardv1.ConditionReady)).To(BeTrue())
+	})
+})
+
+var _ = Describe("usersMatchingRules", func() {
+	It("evaluates the rules for every selected user with failed or successful logins", func() {
+		now := time.Now()
+		failures := map[string][]auditlog.Event{
+			"alice": {{Username: "alice", Timestamp: now, Decision: auditlog.DecisionDeny}},
+			"bob":   {{Username: "bob", Timestamp: now, Decision: auditlog.DecisionDeny}},
+		}
+		logins := map[string][]auditlog.Event{
+			"alice": {{Username: "alice", Timestamp: now, Decision: auditlog.DecisionAllow}},
+			"carol": {{Username: "carol", Timestamp: now, Decision: auditlog.DecisionAllow}},
+			"dave":  {{Username: "dave", Timestamp: now, Decision: auditlog.DecisionAllow}},
+		}
+		compiled, err := compileRules([]guardv1.DetectionRule{
+			{Name: "failed-then-logged-in", Expression: "failures > 0 && logins > 0"},
+			{Name: "logged-in", Expression: "logins > 0"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		matching, err := usersMatchingRules(compiled, failures, logins, nil, rules.Input{Now: now},
+			func(username string, _ []auditlog.Event) bool { return username != "dave" })
+		Expect(err).NotTo(HaveOccurred())
+		Expect(matching).To(Equal(map[string][]string{
+			"alice": {"failed-then-logged-in", "logged-in"},
+			"carol": {"logged-in"},
+		}))
+	})
+})
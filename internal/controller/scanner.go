@@ -0,0 +1,316 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	v1 "github.com/SaifRehman/guarduim/api/v1"
+	"github.com/SaifRehman/guarduim/internal/auditlog"
+)
+
+// cursorKey is the ConfigMap key holding the JSON encoded scanner cursor.
+const cursorKey = "cursor"
+
+// Scanner reads a LogSource on a fixed interval and indexes the failed and
+// successful logins per username, so that every Guarduim shares a single read
+// of the log.
+type Scanner struct {
+	// Name of the log source, used as a metric label.
+	Name   string
+	Source LogSource
+	// Interval between two reads of the log source.
+	Interval time.Duration
+	// Retention is how long indexed logins are kept.
+	Retention time.Duration
+
+	// Client and APIReader persist the cursor in the CursorConfigMap, so a
+	// restarted manager resumes where it stopped. An empty CursorConfigMap
+	// name keeps the cursor in memory only.
+	Client          client.Client
+	APIReader       client.Reader
+	CursorConfigMap types.NamespacedName
+	Log             logr.Logger
+
+	// cursor is only accessed from the scan loop.
+	cursor Cursor
+
+	mu       sync.RWMutex
+	failures map[string][]auditlog.Event
+	logins   map[string][]auditlog.Event
+	scanned  bool
+	err      error
+}
+
+var _ manager.LeaderElectionRunnable = &Scanner{}
+
+// Start implements manager.Runnable.
+func (s *Scanner) Start(ctx context.Context) error {
+	if err := s.loadCursor(ctx); err != nil {
+		s.Log.Error(err, "Failed to load log cursor, reading the log from the start")
+	}
+
+	wait.UntilWithContext(ctx, s.scan, s.Interval)
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable.
+func (s *Scanner) NeedLeaderElection() bool {
+	return true
+}
+
+// Failures returns the indexed failed logins for username recorded after the
+// given time, oldest first.
+func (s *Scanner) Failures(username string, after time.Time) []auditlog.Event {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return eventsAfter(s.failures[username], after)
+}
+
+// FailuresByUser returns the indexed failed logins recorded after the given
+// time by username, oldest first.
+func (s *Scanner) FailuresByUser(after time.Time) map[string][]auditlog.Event {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return eventsByUserAfter(s.failures, after)
+}
+
+// Logins returns the indexed successful logins for username recorded after
+// the given time, oldest first.
+func (s *Scanner) Logins(username string, after time.Time) []auditlog.Event {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return eventsAfter(s.logins[username], after)
+}
+
+// LoginsByUser returns the indexed successful logins recorded after the
+// given time by username, oldest first.
+func (s *Scanner) LoginsByUser(after time.Time) map[string][]auditlog.Event {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return eventsByUserAfter(s.logins, after)
+}
+
+// eventsAfter returns a copy of the events, oldest first, recorded after the
+// given time
+func eventsAfter(events []auditlog.Event, after time.Time) []auditlog.Event {
+	start := sort.Search(len(events), func(i int) bool { return events[i].Timestamp.After(after) })
+	if start == len(events) {
+		return nil
+	}
+	return slices.Clone(events[start:])
+}
+
+// eventsByUserAfter returns eventsAfter for every user of an index, leaving
+// out the users without events after the given time
+func eventsByUserAfter(index map[string][]auditlog.Event, after time.Time) map[string][]auditlog.Event {
+	users := map[string][]auditlog.Event{}
+	for username, events := range index {
+		if events := eventsAfter(events, after); events != nil {
+			users[username] = events
+		}
+	}
+	return users
+}
+
+// Scanned reports whether the log source has been read at least once.
+func (s *Scanner) Scanned() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.scanned
+}
+
+// Err returns the error of the last read of the log source, if any.
+func (s *Scanner) Err() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.err
+}
+
+// scan reads the events written since the previous scan and indexes them.
+func (s *Scanner) scan(ctx context.Context) {
+	start := time.Now()
+	events, cursor, err := s.Source.List(ctx, s.cursor)
+	logScanDuration.WithLabelValues(s.Name).Observe(time.Since(start).Seconds())
+	if err != nil {
+		s.Log.Error(err, "Failed to list authentication events")
+		s.setErr(err)
+		return
+	}
+
+	s.countFailures(ctx, events)
+	s.index(events, time.Now())
+	s.cursor = cursor
+	if err := s.saveCursor(ctx); err != nil {
+		s.Log.Error(err, "Failed to save log cursor")
+	}
+	s.setErr(nil)
+}
+
+// index adds the failed and successful logins among events and drops those
+// older than the retention.
+func (s *Scanner) index(events []auditlog.Event, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failures == nil {
+		s.failures = map[string][]auditlog.Event{}
+	}
+	if s.logins == nil {
+		s.logins = map[string][]auditlog.Event{}
+	}
+
+	var failures, logins []auditlog.Event
+	for _, event := range events {
+		switch {
+		case event.Denied():
+			failures = append(failures, event)
+		case event.Allowed():
+			logins = append(logins, event)
+		}
+	}
+
+	cutoff := now.Add(-s.Retention)
+	indexEvents(s.failures, failures, cutoff)
+	indexEvents(s.logins, logins, cutoff)
+}
+
+// countFailures counts the failed logins among events in authFailuresTotal,
+// labelled with the username if a Guarduim not generated for a policy watches
+// the user, and with otherUsername if not, so that usernames tried against
+// the cluster do not each add a series
+func (s *Scanner) countFailures(ctx context.Context, events []auditlog.Event) {
+	watched := map[string]bool{}
+	if s.Client != nil {
+		guarduims := &v1.GuarduimList{}
+		if err := s.Client.List(ctx, guarduims); err != nil {
+			s.Log.Error(err, "Failed to list Guarduims, counting every failed login as another user")
+		}
+		for i := range guarduims.Items {
+			if username := usernameLabel(&guarduims.Items[i]); username != otherUsername {
+				watched[username] = true
+			}
+		}
+	}
+
+	for _, event := range events {
+		if !event.Denied() {
+			continue
+		}
+		username := otherUsername
+		if watched[event.Username] {
+			username = event.Username
+		}
+		authFailuresTotal.WithLabelValues(username).Inc()
+	}
+}
+
+// indexEvents adds events to an index by username, oldest first, and drops
+// those no later than cutoff
+func indexEvents(index map[string][]auditlog.Event, events []auditlog.Event, cutoff time.Time) {
+	touched := map[string]bool{}
+	for _, event := range events {
+		index[event.Username] = append(index[event.Username], event)
+		touched[event.Username] = true
+	}
+	for username := range touched {
+		events := index[username]
+		sort.SliceStable(events, func(i, j int) bool { return events[i].Timestamp.Before(events[j].Timestamp) })
+	}
+
+	for username, events := range index {
+		start := sort.Search(len(events), func(i int) bool { return events[i].Timestamp.After(cutoff) })
+		if start == len(events) {
+			delete(index, username)
+			continue
+		}
+		index[username] = events[start:]
+	}
+}
+
+func (s *Scanner) setErr(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.scanned = true
+	s.err = err
+}
+
+// loadCursor restores the cursor persisted by a previous manager.
+func (s *Scanner) loadCursor(ctx context.Context) error {
+	if s.CursorConfigMap.Name == "" {
+		return nil
+	}
+
+	configMap := &corev1.ConfigMap{}
+	if err := s.APIReader.Get(ctx, s.CursorConfigMap, configMap); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	data, ok := configMap.Data[cursorKey]
+	if !ok {
+		return nil
+	}
+	cursor := Cursor{}
+	if err := json.Unmarshal([]byte(data), &cursor); err != nil {
+		return err
+	}
+	s.cursor = cursor
+	return nil
+}
+
+// saveCursor persists the cursor in the CursorConfigMap.
+func (s *Scanner) saveCursor(ctx context.Context) error {
+	if s.CursorConfigMap.Name == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(s.cursor)
+	if err != nil {
+		return err
+	}
+
+	configMap := &corev1.ConfigMap{}
+	err = s.APIReader.Get(ctx, s.CursorConfigMap, configMap)
+	if errors.IsNotFound(err) {
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      s.CursorConfigMap.Name,
+				Namespace: s.CursorConfigMap.Namespace,
+				Labels:    map[string]string{"app.kubernetes.io/name": "guarduim"},
+			},
+			Data: map[string]string{cursorKey: string(data)},
+		}
+		return s.Client.Create(ctx, configMap)
+	}
+	if err != nil {
+		return err
+	}
+
+	if configMap.Data == nil {
+		configMap.Data = map[string]string{}
+	}
+	configMap.Data[cursorKey] = string(data)
+	return s.Client.Update(ctx, configMap)
+}
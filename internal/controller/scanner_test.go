@@ -0,0 +1,78 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/SaifRehman/guarduim/internal/auditlog"
+)
+
+var _ = Describe("Scanner", func() {
+	now := time.Now()
+
+	It("indexes failed logins per username", func() {
+		scanner := &Scanner{Source: staticLogSource{
+			{Username: "alice", Decision: auditlog.DecisionDeny, Timestamp: now.Add(-time.Minute)},
+			{Username: "bob", Decision: auditlog.DecisionDeny, Timestamp: now.Add(-2 * time.Minute)},
+			{Username: "alice", Decision: auditlog.DecisionAllow, Timestamp: now.Add(-30 * time.Second)},
+			{Username: "alice", Decision: auditlog.DecisionDeny, Timestamp: now.Add(-3 * time.Minute)},
+		}, Retention: time.Hour}
+		Expect(scanner.Scanned()).To(BeFalse())
+
+		scanner.scan(context.Background())
+		Expect(scanner.Scanned()).To(BeTrue())
+		Expect(scanner.Err()).NotTo(HaveOccurred())
+
+		failures := scanner.Failures("alice", time.Time{})
+		Expect(failures).To(HaveLen(2))
+		Expect(failures[0].Timestamp).To(Equal(now.Add(-3 * time.Minute)))
+		Expect(scanner.Failures("alice", now.Add(-2*time.Minute))).To(HaveLen(1))
+		Expect(scanner.Failures("carol", time.Time{})).To(BeEmpty())
+	})
+
+	It("indexes successful logins apart from failures", func() {
+		scanner := &Scanner{Source: staticLogSource{
+			{Username: "alice", Decision: auditlog.DecisionDeny, Timestamp: now.Add(-time.Minute)},
+			{Username: "alice", Decision: auditlog.DecisionAllow, Timestamp: now.Add(-30 * time.Second)},
+			{Username: "alice", Decision: auditlog.DecisionError, Timestamp: now.Add(-20 * time.Second)},
+		}, Retention: time.Hour}
+
+		scanner.scan(context.Background())
+		logins := scanner.Logins("alice", time.Time{})
+		Expect(logins).To(HaveLen(1))
+		Expect(logins[0].Timestamp).To(Equal(now.Add(-30 * time.Second)))
+		Expect(scanner.Logins("alice", now.Add(-10*time.Second))).To(BeEmpty())
+		Expect(scanner.LoginsByUser(time.Time{})).To(HaveKey("alice"))
+		Expect(scanner.Failures("alice", time.Time{})).To(HaveLen(1))
+	})
+
+	It("drops failures older than the retention", func() {
+		scanner := &Scanner{Source: staticLogSource{
+			{Username: "alice", Decision: auditlog.DecisionDeny, Timestamp: now.Add(-2 * time.Hour)},
+			{Username: "bob", Decision: auditlog.DecisionDeny, Timestamp: now.Add(-3 * time.Hour)},
+		}, Retention: time.Hour}
+
+		scanner.scan(context.Background())
+		Expect(scanner.Failures("alice", time.Time{})).To(BeEmpty())
+		Expect(scanner.failures).To(BeEmpty())
+	})
+})
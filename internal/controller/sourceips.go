@@ -0,0 +1,137 @@
+package controller
+
+import (
+	"context"
+	"net/netip"
+	"slices"
+
+	v1 "github.com/SaifRehman/guarduim/api/v1"
+	"github.com/SaifRehman/guarduim/internal/auditlog"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// Prefix lengths of the client address ranges when the policy sets none
+const (
+	defaultIPv4PrefixLength = 32
+	defaultIPv6PrefixLength = 64
+)
+
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete
+
+// blockedSourceRanges returns the client address ranges, in CIDR notation,
+// with more failures than the threshold of blocking, whatever users they were for
+func blockedSourceRanges(failures map[string][]auditlog.Event, blocking v1.SourceIPBlocking) []string {
+	counts := map[netip.Prefix]int{}
+	for _, events := range failures {
+		for _, event := range events {
+			if prefix, ok := sourceRange(event.ClientIP(), blocking); ok {
+				counts[prefix]++
+			}
+		}
+	}
+
+	var ranges []string
+	for prefix, count := range counts {
+		if count > blocking.Threshold {
+			ranges = append(ranges, prefix.String())
+		}
+	}
+	slices.Sort(ranges)
+	return ranges
+}
+
+// sourceRange returns the range of the client address for blocking
+func sourceRange(address string, blocking v1.SourceIPBlocking) (netip.Prefix, bool) {
+	addr, err := netip.ParseAddr(address)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	addr = addr.Unmap().WithZone("")
+
+	bits := blocking.IPv6PrefixLength
+	if bits == 0 {
+		bits = defaultIPv6PrefixLength
+	}
+	if addr.Is4() {
+		bits = blocking.IPv4PrefixLength
+		if bits == 0 {
+			bits = defaultIPv4PrefixLength
+		}
+	}
+	prefix, err := addr.Prefix(bits)
+	return prefix, err == nil
+}
+
+// networkPolicyName returns the name of the NetworkPolicy blocking the
+// source ranges of a policy, with long policy names cut short
+func networkPolicyName(policy *v1.GuarduimPolicy) string {
+	return objectName("guarduim-"+policy.Name, "")
+}
+
+// applySourceIPBlocking denies the ranges access to the pods selected by the
+// policy with a NetworkPolicy, and deletes the NetworkPolicies of the policy
+// that are no longer needed.
+//
+// NetworkPolicies are additive: the ranges are still let in if another
+// NetworkPolicy selecting the pods allows them, e.g. from everywhere. Pods no
+// NetworkPolicy selected before are isolated by this one, which only allows
+// ingress from addresses outside the ranges: whether traffic from other pods
+// or from the nodes matches such an ipBlock depends on the network plugin.
+func (r *GuarduimPolicyReconciler) applySourceIPBlocking(ctx context.Context, policy *v1.GuarduimPolicy, ranges []string) error {
+	blocking := policy.Spec.SourceIPBlocking
+	var desired client.ObjectKey
+	if blocking != nil && len(ranges) > 0 {
+		desired = client.ObjectKey{Namespace: blocking.Namespace, Name: networkPolicyName(policy)}
+	}
+
+	existing := &networkingv1.NetworkPolicyList{}
+	if err := r.Client.List(ctx, existing, policyLabels(policy)); err != nil {
+		return err
+	}
+	for i := range existing.Items {
+		networkPolicy := &existing.Items[i]
+		if client.ObjectKeyFromObject(networkPolicy) == desired {
+			continue
+		}
+		if err := r.Client.Delete(ctx, networkPolicy); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	if desired.Name == "" {
+		return nil
+	}
+
+	networkPolicy := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: desired.Namespace, Name: desired.Name},
+	}
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, networkPolicy, func() error {
+		if networkPolicy.Labels == nil {
+			networkPolicy.Labels = map[string]string{}
+		}
+		networkPolicy.Labels[v1.PolicyLabel] = string(policy.UID)
+		networkPolicy.Spec = networkingv1.NetworkPolicySpec{
+			PodSelector: blocking.PodSelector,
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress:     []networkingv1.NetworkPolicyIngressRule{{From: exceptRanges(ranges)}},
+		}
+		return controllerutil.SetControllerReference(policy, networkPolicy, r.Scheme)
+	})
+	return err
+}
+
+// exceptRanges returns peers matching every address outside of ranges
+func exceptRanges(ranges []string) []networkingv1.NetworkPolicyPeer {
+	ipv4 := &networkingv1.IPBlock{CIDR: "0.0.0.0/0"}
+	ipv6 := &networkingv1.IPBlock{CIDR: "::/0"}
+	for _, cidr := range ranges {
+		if netip.MustParsePrefix(cidr).Addr().Is4() {
+			ipv4.Except = append(ipv4.Except, cidr)
+		} else {
+			ipv6.Except = append(ipv6.Except, cidr)
+		}
+	}
+	return []networkingv1.NetworkPolicyPeer{{IPBlock: ipv4}, {IPBlock: ipv6}}
+}
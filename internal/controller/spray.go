@@ -0,0 +1,172 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strings"
+
+	v1 "github.com/SaifRehman/guarduim/api/v1"
+	"github.com/SaifRehman/guarduim/internal/auditlog"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// maxIncidentUsernames bounds the usernames kept in GuarduimIncidentStatus.
+const maxIncidentUsernames = 100
+
+//+kubebuilder:rbac:groups=guard.guarduim.com,resources=guarduimincidents,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=guard.guarduim.com,resources=guarduimincidents/status,verbs=get;update;patch
+
+// spraySource is a source of failed logins across users
+type spraySource struct {
+	sourceIP  string
+	userAgent string
+
+	usernames sets.Set[string]
+	// events are the failed logins from the source, oldest first
+	events []auditlog.Event
+	// loggedIn are the usernames the source logged in as
+	loggedIn sets.Set[string]
+}
+
+// incidentType returns the kind of attack of the source
+func (s *spraySource) incidentType() v1.IncidentType {
+	if s.loggedIn.Len() > 0 {
+		return v1.IncidentCredentialStuffing
+	}
+	return v1.IncidentPasswordSpray
+}
+
+// detectSprays groups failures by source and returns the sources that failed
+// to log in as more usernames than the threshold of detection, sorted, with
+// the usernames they logged in as among logins
+func detectSprays(failures, logins map[string][]auditlog.Event, detection v1.SprayDetection) []*spraySource {
+	type sourceKey struct{ sourceIP, userAgent string }
+	keyOf := func(event auditlog.Event) sourceKey {
+		key := sourceKey{sourceIP: event.ClientIP()}
+		if detection.ByUserAgent {
+			key.userAgent = event.UserAgent
+		}
+		return key
+	}
+
+	sources := map[sourceKey]*spraySource{}
+	for username, events := range failures {
+		for _, event := range events {
+			key := keyOf(event)
+			if key.sourceIP == "" {
+				continue
+			}
+
+			source, ok := sources[key]
+			if !ok {
+				source = &spraySource{sourceIP: key.sourceIP, userAgent: key.userAgent,
+					usernames: sets.New[string](), loggedIn: sets.New[string]()}
+				sources[key] = source
+			}
+			source.usernames.Insert(username)
+			source.events = append(source.events, event)
+		}
+	}
+	for username, events := range logins {
+		for _, event := range events {
+			if source, ok := sources[keyOf(event)]; ok {
+				source.loggedIn.Insert(username)
+			}
+		}
+	}
+
+	var sprays []*spraySource
+	for _, source := range sources {
+		if source.usernames.Len() > detection.UsernameThreshold {
+			slices.SortStableFunc(source.events, func(a, b auditlog.Event) int { return a.Timestamp.Compare(b.Timestamp) })
+			sprays = append(sprays, source)
+		}
+	}
+	slices.SortFunc(sprays, func(a, b *spraySource) int {
+		return strings.Compare(a.sourceIP+"\x00"+a.userAgent, b.sourceIP+"\x00"+b.userAgent)
+	})
+	return sprays
+}
+
+// incidentName returns the name of the incident of a source for a policy,
+// one per type of attack. Sources are hashed, addresses and user agents may
+// contain characters names can not, and long policy names cut short.
+func incidentName(policy *v1.GuarduimPolicy, source *spraySource) string {
+	kind := "spray"
+	if source.incidentType() == v1.IncidentCredentialStuffing {
+		kind = "stuffing"
+	}
+	sum := sha256.Sum256([]byte(source.sourceIP + "\x00" + source.userAgent))
+	return objectName(policy.Name, "-"+kind+"-"+hex.EncodeToString(sum[:5]))
+}
+
+// raiseIncident creates the incident of a spraying source, or adds the
+// failures seen since its last update to it
+func (r *GuarduimPolicyReconciler) raiseIncident(ctx context.Context, policy *v1.GuarduimPolicy, source *spraySource) error {
+	incident := &v1.GuarduimIncident{ObjectMeta: metav1.ObjectMeta{Name: incidentName(policy, source)}}
+	err := r.Client.Get(ctx, client.ObjectKeyFromObject(incident), incident)
+	if errors.IsNotFound(err) {
+		incident.Labels = policyLabels(policy)
+		incident.Spec = v1.GuarduimIncidentSpec{
+			Type:      source.incidentType(),
+			SourceIP:  source.sourceIP,
+			UserAgent: source.userAgent,
+		}
+		if err := controllerutil.SetControllerReference(policy, incident, r.Scheme); err != nil {
+			return err
+		}
+		if err := r.Client.Create(ctx, incident); err != nil {
+			return err
+		}
+		incidentsTotal.WithLabelValues(string(incident.Spec.Type)).Inc()
+		if incident.Spec.Type == v1.IncidentCredentialStuffing {
+			r.Recorder.Eventf(policy, corev1.EventTypeWarning, eventCredentialStuffing,
+				"Source %s failed to log in as %d users and logged in as %d of them, raised GuarduimIncident %s",
+				source.sourceIP, source.usernames.Len(), source.loggedIn.Len(), incident.Name)
+		} else {
+			r.Recorder.Eventf(policy, corev1.EventTypeWarning, eventPasswordSpray,
+				"Source %s failed to log in as %d users, raised GuarduimIncident %s",
+				source.sourceIP, source.usernames.Len(), incident.Name)
+		}
+	} else if err != nil {
+		return err
+	}
+
+	status := &incident.Status
+	lastSeen := status.LastSeen
+	usernames := sets.New(status.Usernames...)
+	for _, event := range source.events {
+		if !lastSeen.IsZero() && !event.Timestamp.After(lastSeen.Time) {
+			continue
+		}
+		if status.FirstSeen.IsZero() {
+			status.FirstSeen = metav1.MicroTime{Time: event.Timestamp}
+		}
+		status.LastSeen = metav1.MicroTime{Time: event.Timestamp}
+		status.FailureCount++
+		if usernames.Len() < maxIncidentUsernames {
+			usernames.Insert(event.Username)
+		}
+	}
+	loggedIn := sets.New(status.LoggedInUsernames...)
+	for _, username := range sets.List(source.loggedIn) {
+		if loggedIn.Len() < maxIncidentUsernames {
+			loggedIn.Insert(username)
+		}
+	}
+	if status.LastSeen.Equal(&lastSeen) && loggedIn.Len() == len(status.LoggedInUsernames) {
+		// Nothing new from the source
+		return nil
+	}
+	status.Usernames = sets.List(usernames)
+	status.LoggedInUsernames = sets.List(loggedIn)
+	status.UsernameCount = max(status.UsernameCount, source.usernames.Len())
+	return r.Client.Status().Update(ctx, incident)
+}
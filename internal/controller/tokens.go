@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// OpenShift OAuth tokens, absent on plain Kubernetes clusters
+var (
+	accessTokenGVR    = schema.GroupVersionResource{Group: "oauth.openshift.io", Version: "v1", Resource: "oauthaccesstokens"}
+	authorizeTokenGVR = schema.GroupVersionResource{Group: "oauth.openshift.io", Version: "v1", Resource: "oauthauthorizetokens"}
+)
+
+// revokeTokens deletes the OAuth access tokens of username, and its authorize
+// tokens if authorizeTokens is set, and returns how many were deleted
+func (r *GuarduimReconciler) revokeTokens(ctx context.Context, username string, authorizeTokens bool) (int, error) {
+	resources := []schema.GroupVersionResource{accessTokenGVR}
+	if authorizeTokens {
+		resources = append(resources, authorizeTokenGVR)
+	}
+
+	revoked := 0
+	for _, resource := range resources {
+		n, err := r.deleteUserTokens(ctx, resource, username)
+		revoked += n
+		if err != nil {
+			return revoked, err
+		}
+	}
+	return revoked, nil
+}
+
+// deleteUserTokens deletes the tokens of the given resource issued to username
+func (r *GuarduimReconciler) deleteUserTokens(ctx context.Context, resource schema.GroupVersionResource, username string) (int, error) {
+	tokens, err := r.Dynamic.Resource(resource).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("userName", username).String(),
+	})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	deleted := 0
+	for _, token := range tokens.Items {
+		// Never delete another user's session if the field selector was ignored
+		if userName, _, _ := unstructured.NestedString(token.Object, "userName"); userName != username {
+			continue
+		}
+		err := r.Dynamic.Resource(resource).Delete(ctx, token.GetName(), metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return deleted, err
+		}
+		if err == nil {
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+// tokenListKinds lets the fake dynamic client list OAuth tokens.
+var tokenListKinds = map[schema.GroupVersionResource]string{
+	accessTokenGVR:    "OAuthAccessTokenList",
+	authorizeTokenGVR: "OAuthAuthorizeTokenList",
+}
+
+var _ = Describe("OAuth token revocation", func() {
+	ctx := context.Background()
+
+	token := func(kind, name, username string) runtime.Object {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "oauth.openshift.io/v1",
+			"kind":       kind,
+			"metadata":   map[string]interface{}{"name": name},
+			"userName":   username,
+		}}
+	}
+
+	var reconciler *GuarduimReconciler
+
+	BeforeEach(func() {
+		reconciler = &GuarduimReconciler{Dynamic: dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), tokenListKinds,
+			token("OAuthAccessToken", "sha256~alice-1", "alice"),
+			token("OAuthAccessToken", "sha256~alice-2", "alice"),
+			token("OAuthAccessToken", "sha256~bob-1", "bob"),
+			token("OAuthAuthorizeToken", "sha256~alice-code", "alice"),
+		)}
+	})
+
+	remaining := func(resource schema.GroupVersionResource) []string {
+		tokens, err := reconciler.Dynamic.Resource(resource).List(ctx, metav1.ListOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		var names []string
+		for _, token := range tokens.Items {
+			names = append(names, token.GetName())
+		}
+		return names
+	}
+
+	It("deletes only the access tokens of the user", func() {
+		Expect(reconciler.revokeTokens(ctx, "alice", false)).To(Equal(2))
+		Expect(remaining(accessTokenGVR)).To(ConsistOf("sha256~bob-1"))
+		Expect(remaining(authorizeTokenGVR)).To(ConsistOf("sha256~alice-code"))
+	})
+
+	It("deletes the authorize tokens of the user when asked to", func() {
+		Expect(reconciler.revokeTokens(ctx, "alice", true)).To(Equal(3))
+		Expect(remaining(authorizeTokenGVR)).To(BeEmpty())
+	})
+})
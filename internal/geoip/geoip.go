@@ -0,0 +1,123 @@
+// Package geoip looks up where client addresses are registered in MaxMind
+// format (mmdb) databases, such as GeoLite2 City, Country and ASN.
+package geoip
+
+import (
+	"errors"
+	"math"
+	"net"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// earthRadius is the mean radius of the Earth in kilometres.
+const earthRadius = 6371.0
+
+// Location is where an address is registered. Fields the databases do not
+// hold are left zero.
+type Location struct {
+	// Country is the ISO 3166-1 code of the country.
+	Country string
+	// ASN is the autonomous system number, and Organization its owner.
+	ASN          uint
+	Organization string
+
+	// HasCoordinates is set when Latitude and Longitude are known.
+	HasCoordinates      bool
+	Latitude, Longitude float64
+}
+
+// record holds the fields of the City, Country and ASN databases.
+type record struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	RegisteredCountry struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"registered_country"`
+	Location struct {
+		Latitude  *float64 `maxminddb:"latitude"`
+		Longitude *float64 `maxminddb:"longitude"`
+	} `maxminddb:"location"`
+	ASN          uint   `maxminddb:"autonomous_system_number"`
+	Organization string `maxminddb:"autonomous_system_organization"`
+}
+
+// Database looks up addresses in one or more mmdb files, e.g. a City and an
+// ASN database.
+type Database struct {
+	readers []*maxminddb.Reader
+}
+
+// Open opens the mmdb files at paths.
+func Open(paths ...string) (*Database, error) {
+	if len(paths) == 0 {
+		return nil, errors.New("no GeoIP database given")
+	}
+
+	db := &Database{}
+	for _, path := range paths {
+		reader, err := maxminddb.Open(path)
+		if err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+		db.readers = append(db.readers, reader)
+	}
+	return db, nil
+}
+
+// Lookup returns the location of address, merged from every database, and
+// whether any database holds it.
+func (d *Database) Lookup(address string) (Location, bool) {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return Location{}, false
+	}
+
+	var location Location
+	for _, reader := range d.readers {
+		var r record
+		if err := reader.Lookup(ip, &r); err != nil {
+			continue
+		}
+
+		if location.Country == "" {
+			location.Country = r.Country.ISOCode
+		}
+		if location.Country == "" {
+			location.Country = r.RegisteredCountry.ISOCode
+		}
+		if location.ASN == 0 {
+			location.ASN, location.Organization = r.ASN, r.Organization
+		}
+		if !location.HasCoordinates && r.Location.Latitude != nil && r.Location.Longitude != nil {
+			location.HasCoordinates = true
+			location.Latitude, location.Longitude = *r.Location.Latitude, *r.Location.Longitude
+		}
+	}
+	return location, location != (Location{})
+}
+
+// Close closes the mmdb files.
+func (d *Database) Close() error {
+	var errs []error
+	for _, reader := range d.readers {
+		errs = append(errs, reader.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Distance returns the great-circle distance between two locations with
+// coordinates, in kilometres.
+func Distance(a, b Location) float64 {
+	lat1, lat2 := radians(a.Latitude), radians(b.Latitude)
+	dLat, dLon := lat2-lat1, radians(b.Longitude-a.Longitude)
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
@@ -0,0 +1,189 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// writeDatabase writes an IPv4 mmdb file holding a record for each network,
+// for a small fixture without a database writer
+func writeDatabase(name string, records map[string]map[string]any) string {
+	// The search tree has a node per bit of the networks, children are node
+	// indexes, 0 for no data, or -1-offset for a record in the data section
+	nodes := [][2]int{{}}
+	var data bytes.Buffer
+	for network, record := range records {
+		prefix := netip.MustParsePrefix(network)
+		address := prefix.Addr().As4()
+		node := 0
+		for i := range prefix.Bits() {
+			bit := address[i/8] >> (7 - i%8) & 1
+			if i == prefix.Bits()-1 {
+				nodes[node][bit] = -1 - data.Len()
+				encodeData(&data, record)
+				break
+			}
+			if nodes[node][bit] == 0 {
+				nodes = append(nodes, [2]int{})
+				nodes[node][bit] = len(nodes) - 1
+			}
+			node = nodes[node][bit]
+		}
+	}
+
+	var file bytes.Buffer
+	for _, node := range nodes {
+		for _, child := range node {
+			value := len(nodes)
+			if child > 0 {
+				value = child
+			} else if child < 0 {
+				value = len(nodes) + 16 - 1 - child
+			}
+			file.Write([]byte{byte(value >> 16), byte(value >> 8), byte(value)})
+		}
+	}
+	file.Write(make([]byte, 16))
+	file.Write(data.Bytes())
+	file.WriteString("\xAB\xCD\xEFMaxMind.com")
+	encodeData(&file, map[string]any{
+		"binary_format_major_version": uint(2),
+		"binary_format_minor_version": uint(0),
+		"database_type":               name,
+		"ip_version":                  uint(4),
+		"node_count":                  uint(len(nodes)),
+		"record_size":                 uint(24),
+	})
+
+	path := filepath.Join(GinkgoT().TempDir(), name+".mmdb")
+	Expect(os.WriteFile(path, file.Bytes(), 0o600)).To(Succeed())
+	return path
+}
+
+// encodeData appends value to buf in the mmdb data format, for the strings,
+// doubles, unsigned integers and maps of the fixtures
+func encodeData(buf *bytes.Buffer, value any) {
+	control := func(kind, size int) {
+		Expect(size).To(BeNumerically("<", 285))
+		if size < 29 {
+			buf.WriteByte(byte(kind<<5 | size))
+		} else {
+			buf.Write([]byte{byte(kind<<5 | 29), byte(size - 29)})
+		}
+	}
+	switch v := value.(type) {
+	case string:
+		control(2, len(v))
+		buf.WriteString(v)
+	case float64:
+		control(3, 8)
+		Expect(binary.Write(buf, binary.BigEndian, v)).To(Succeed())
+	case uint:
+		control(6, 4)
+		Expect(binary.Write(buf, binary.BigEndian, uint32(v))).To(Succeed())
+	case map[string]any:
+		control(7, len(v))
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			encodeData(buf, key)
+			encodeData(buf, v[key])
+		}
+	default:
+		Fail("unsupported mmdb value")
+	}
+}
+
+var _ = Describe("Distance", func() {
+	It("returns the great-circle distance in kilometres", func() {
+		paris := Location{HasCoordinates: true, Latitude: 48.8566, Longitude: 2.3522}
+		newYork := Location{HasCoordinates: true, Latitude: 40.7128, Longitude: -74.0060}
+		Expect(Distance(paris, newYork)).To(BeNumerically("~", 5837, 5))
+		Expect(Distance(paris, paris)).To(BeZero())
+	})
+})
+
+var _ = Describe("Database", func() {
+	It("merges the locations of a City and an ASN database", func() {
+		city := writeDatabase("GeoLite2-City", map[string]map[string]any{
+			"192.0.2.0/24": {
+				"country":            map[string]any{"iso_code": "FR"},
+				"registered_country": map[string]any{"iso_code": "DE"},
+				"location":           map[string]any{"latitude": 48.8566, "longitude": 2.3522},
+			},
+			"198.51.100.0/24": {
+				"registered_country": map[string]any{"iso_code": "US"},
+			},
+		})
+		asn := writeDatabase("GeoLite2-ASN", map[string]map[string]any{
+			"192.0.2.0/24": {
+				"autonomous_system_number":       uint(3215),
+				"autonomous_system_organization": "Orange",
+			},
+			"203.0.113.0/24": {
+				"autonomous_system_number": uint(64500),
+			},
+		})
+		db, err := Open(city, asn)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(db.Close)
+		lookup := func(address string) Location {
+			location, ok := db.Lookup(address)
+			Expect(ok).To(BeTrue(), address)
+			return location
+		}
+
+		Expect(lookup("192.0.2.1")).To(Equal(Location{Country: "FR", ASN: 3215, Organization: "Orange",
+			HasCoordinates: true, Latitude: 48.8566, Longitude: 2.3522}))
+
+		By("falling back to the registered country")
+		Expect(lookup("198.51.100.7")).To(Equal(Location{Country: "US"}))
+
+		By("returning what only one database holds")
+		Expect(lookup("203.0.113.9")).To(Equal(Location{ASN: 64500}))
+
+		By("not finding addresses outside the databases")
+		for _, address := range []string{"10.0.0.1", "2001:db8::1", "router"} {
+			_, ok := db.Lookup(address)
+			Expect(ok).To(BeFalse(), address)
+		}
+	})
+})
+
+var _ = Describe("Open", func() {
+	It("fails without a database", func() {
+		_, err := Open()
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("fails on a missing file", func() {
+		_, err := Open(filepath.Join(GinkgoT().TempDir(), "GeoLite2-City.mmdb"))
+		Expect(err).To(HaveOccurred())
+	})
+})
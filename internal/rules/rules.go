@@ -0,0 +1,197 @@
+// Package rules evaluates custom detection rules, CEL expressions, against the
+// login attempts of a user, e.g. "failures_10m > 5 && distinct_ips > 2".
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/cel-go/cel"
+
+	"github.com/SaifRehman/guarduim/internal/auditlog"
+)
+
+// costLimit bounds the work of a single evaluation, so that a rule iterating
+// over many events can not stall the reconciler.
+const costLimit = 1000000
+
+// RecentWindow is the longest duration of the failures_<duration> variables.
+const RecentWindow = time.Hour
+
+// recentWindows are the durations of the failures_<duration> variables.
+var recentWindows = []struct {
+	name     string
+	duration time.Duration
+}{
+	{"1m", time.Minute},
+	{"5m", 5 * time.Minute},
+	{"10m", 10 * time.Minute},
+	{"1h", RecentWindow},
+}
+
+// Input is what a rule is evaluated against, the login attempts of a user
+// within a window.
+type Input struct {
+	Username string
+	// Failures and Logins are the failed and successful logins, oldest first.
+	Failures, Logins []auditlog.Event
+	// RecentFailures are the failed logins in the last RecentWindow, oldest
+	// first, whatever the window, for the failures_<duration> variables.
+	// Failures are used if nil.
+	RecentFailures []auditlog.Event
+	// Now is the end of the window.
+	Now time.Time
+}
+
+// Rule is a compiled detection rule.
+type Rule struct {
+	Name    string
+	program cel.Program
+}
+
+// env declares the variables rules can use:
+//
+//	username              string
+//	now                   timestamp, the end of the window
+//	failures, logins      int, failed and successful logins
+//	failures_1m, _5m, _10m, _1h
+//	                      int, failed logins in the last minute, 5 minutes...
+//	                      among the recent failures, whatever the window
+//	distinct_ips          int, client addresses of the failed logins
+//	distinct_user_agents  int, user agents of the failed logins
+//	events                list of every login attempt, oldest first, as maps
+//	                      with timestamp, source_ip, user_agent,
+//	                      identity_provider and allowed keys
+var env = sync.OnceValues(func() (*cel.Env, error) {
+	options := []cel.EnvOption{
+		cel.Variable("username", cel.StringType),
+		cel.Variable("now", cel.TimestampType),
+		cel.Variable("failures", cel.IntType),
+		cel.Variable("logins", cel.IntType),
+		cel.Variable("distinct_ips", cel.IntType),
+		cel.Variable("distinct_user_agents", cel.IntType),
+		cel.Variable("events", cel.ListType(cel.MapType(cel.StringType, cel.DynType))),
+	}
+	for _, window := range recentWindows {
+		options = append(options, cel.Variable("failures_"+window.name, cel.IntType))
+	}
+	return cel.NewEnv(options...)
+})
+
+// Compile compiles the expression of a rule, which must evaluate to a bool.
+func Compile(name, expression string) (*Rule, error) {
+	e, err := env()
+	if err != nil {
+		return nil, err
+	}
+
+	ast, issues := e.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	// Fields of events are dyn, checked when evaluated
+	if t := ast.OutputType(); t != cel.BoolType && t != cel.DynType {
+		return nil, fmt.Errorf("must evaluate to a bool, not %s", t)
+	}
+	program, err := e.Program(ast, cel.CostLimit(costLimit))
+	if err != nil {
+		return nil, err
+	}
+	return &Rule{Name: name, program: program}, nil
+}
+
+// Matches evaluates the rule against input.
+func (r *Rule) Matches(input Input) (bool, error) {
+	out, _, err := r.program.Eval(variables(input))
+	if err != nil {
+		return false, fmt.Errorf("rule %q: %w", r.Name, err)
+	}
+	matched, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("rule %q: evaluated to %v, not a bool", r.Name, out.Value())
+	}
+	return matched, nil
+}
+
+// Matching returns the names of rules matching input. A rule failing to
+// evaluate does not match, its error is returned along with the other
+// matches.
+func Matching(rules []*Rule, input Input) ([]string, error) {
+	var names []string
+	var errs []error
+	for _, rule := range rules {
+		matched, err := rule.Matches(input)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if matched {
+			names = append(names, rule.Name)
+		}
+	}
+	return names, errors.Join(errs...)
+}
+
+// variables returns the aggregates of input declared in env
+func variables(input Input) map[string]any {
+	ips := map[string]bool{}
+	userAgents := map[string]bool{}
+	for _, event := range input.Failures {
+		if ip := event.ClientIP(); ip != "" {
+			ips[ip] = true
+		}
+		if event.UserAgent != "" {
+			userAgents[event.UserAgent] = true
+		}
+	}
+
+	vars := map[string]any{
+		"username":             input.Username,
+		"now":                  input.Now,
+		"failures":             len(input.Failures),
+		"logins":               len(input.Logins),
+		"distinct_ips":         len(ips),
+		"distinct_user_agents": len(userAgents),
+		"events":               events(input),
+	}
+	recent := input.RecentFailures
+	if recent == nil {
+		recent = input.Failures
+	}
+	for _, window := range recentWindows {
+		start := input.Now.Add(-window.duration)
+		count := 0
+		for _, event := range recent {
+			if event.Timestamp.After(start) {
+				count++
+			}
+		}
+		vars["failures_"+window.name] = count
+	}
+	return vars
+}
+
+// events returns the failed and successful logins of input, oldest first, as
+// CEL maps
+func events(input Input) []map[string]any {
+	list := make([]map[string]any, 0, len(input.Failures)+len(input.Logins))
+	failures, logins := input.Failures, input.Logins
+	for len(failures) > 0 || len(logins) > 0 {
+		var event auditlog.Event
+		if len(logins) == 0 || (len(failures) > 0 && !logins[0].Timestamp.Before(failures[0].Timestamp)) {
+			event, failures = failures[0], failures[1:]
+		} else {
+			event, logins = logins[0], logins[1:]
+		}
+		list = append(list, map[string]any{
+			"timestamp":         event.Timestamp,
+			"source_ip":         event.ClientIP(),
+			"user_agent":        event.UserAgent,
+			"identity_provider": event.IdentityProvider(),
+			"allowed":           event.Allowed(),
+		})
+	}
+	return list
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/SaifRehman/guarduim/internal/auditlog"
+)
+
+var _ = Describe("Compile", func() {
+	It("compiles boolean expressions over the declared variables", func() {
+		_, err := Compile("burst", `failures_10m > 5 && distinct_ips > 2`)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = Compile("scripted", `events.exists(e, !e.allowed && e.user_agent.startsWith("python"))`)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("rejects syntax errors, unknown variables and non-boolean expressions", func() {
+		_, err := Compile("syntax", `failures >`)
+		Expect(err).To(HaveOccurred())
+		_, err = Compile("unknown", `failures_2d > 5`)
+		Expect(err).To(MatchError(ContainSubstring("failures_2d")))
+		_, err = Compile("count", `failures + 1`)
+		Expect(err).To(MatchError(ContainSubstring("must evaluate to a bool")))
+	})
+})
+
+var _ = Describe("Matching", func() {
+	var (
+		now   time.Time
+		input Input
+	)
+
+	BeforeEach(func() {
+		now = time.Now()
+		failure := func(ago time.Duration, ip, userAgent string) auditlog.Event {
+			return auditlog.Event{Timestamp: now.Add(-ago), Username: "alice", Decision: auditlog.DecisionDeny,
+				SourceIPs: []string{ip}, UserAgent: userAgent, RequestURI: "/login/htpasswd"}
+		}
+		input = Input{
+			Username: "alice",
+			Now:      now,
+			Failures: []auditlog.Event{
+				failure(30*time.Minute, "192.0.2.1", "curl/8.0"),
+				failure(8*time.Minute, "192.0.2.2", "curl/8.0"),
+				failure(4*time.Minute, "192.0.2.3", "python-requests/2.31"),
+				failure(30*time.Second, "192.0.2.3", "python-requests/2.31"),
+			},
+			Logins: []auditlog.Event{{Timestamp: now.Add(-time.Minute), Username: "alice",
+				Decision: auditlog.DecisionAllow, SourceIPs: []string{"198.51.100.7"}}},
+		}
+	})
+
+	It("evaluates the aggregates of the failed and successful logins", func() {
+		for expression, expected := range map[string]bool{
+			`failures == 4 && logins == 1`:                                    true,
+			`failures_1m == 1 && failures_5m == 2`:                            true,
+			`failures_10m == 3 && failures_1h == 4`:                           true,
+			`distinct_ips == 3 && distinct_user_agents == 2`:                  true,
+			`failures_10m > 5 && distinct_ips > 2`:                            false,
+			`username.startsWith("admin")`:                                    false,
+			`now - events[0].timestamp > duration("20m")`:                     true,
+			`events[3].allowed && events[3].source_ip != "192.0.2.3"`:         true,
+			`events.filter(e, e.identity_provider == "htpasswd").size() == 4`: true,
+		} {
+			rule, err := Compile("rule", expression)
+			Expect(err).NotTo(HaveOccurred(), expression)
+			Expect(rule.Matches(input)).To(Equal(expected), expression)
+		}
+	})
+
+	It("counts the recent failures beyond a shorter window", func() {
+		// A 5 minute window, with the failures of the last hour
+		input.RecentFailures = input.Failures
+		input.Failures = input.Failures[2:]
+		rule, err := Compile("rule", `failures == 2 && failures_10m == 3 && failures_1h == 4`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rule.Matches(input)).To(BeTrue())
+	})
+
+	It("returns the matching rules and the evaluation errors", func() {
+		var compiled []*Rule
+		for name, expression := range map[string]string{
+			"burst":      `failures_10m > 2`,
+			"quiet":      `failures == 0`,
+			"outOfRange": `events[10].allowed`,
+		} {
+			rule, err := Compile(name, expression)
+			Expect(err).NotTo(HaveOccurred())
+			compiled = append(compiled, rule)
+		}
+
+		names, err := Matching(compiled, input)
+		Expect(names).To(ConsistOf("burst"))
+		Expect(err).To(MatchError(ContainSubstring(`rule "outOfRange"`)))
+	})
+})
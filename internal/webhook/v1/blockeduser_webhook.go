@@ -0,0 +1,110 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	guardv1 "github.com/SaifRehman/guarduim/api/v1"
+)
+
+// BlockedUserPath is the path the blocked user webhook is served on.
+const BlockedUserPath = "/validate-blocked-user"
+
+// UsernameField indexes Guarduim objects by spec.username.
+const UsernameField = "spec.username"
+
+// systemUserPrefix marks cluster components, which never log in through the
+// OAuth server and are therefore never denied, so that a Guarduim can not lock
+// out the API server, the controllers or this manager itself. The
+// matchConditions of config/webhook/blockeduser_matchconditions_patch.yaml
+// keep their requests from reaching the webhook at all.
+const systemUserPrefix = "system:"
+
+var blockeduserlog = logf.Log.WithName("blocked-user")
+
+// SetupBlockedUserWebhookWithManager indexes Guarduim objects by username and
+// registers the blocked user webhook in the manager.
+func SetupBlockedUserWebhookWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &guardv1.Guarduim{}, UsernameField, IndexUsername); err != nil {
+		return err
+	}
+
+	mgr.GetWebhookServer().Register(BlockedUserPath, &webhook.Admission{
+		Handler: &BlockedUserValidator{Client: mgr.GetClient()},
+	})
+	return nil
+}
+
+// IndexUsername is the UsernameField index function.
+func IndexUsername(obj client.Object) []string {
+	return []string{obj.(*guardv1.Guarduim).Spec.Username}
+}
+
+// Admission webhooks only see requests that change the cluster, reads are
+// covered by the revoked role bindings.
+// +kubebuilder:webhook:path=/validate-blocked-user,mutating=false,failurePolicy=ignore,sideEffects=None,groups=*,resources=*;*/*,verbs=create;update;delete;connect,versions=*,name=vblockeduser.kb.io,admissionReviewVersions=v1,matchPolicy=Equivalent,timeoutSeconds=5
+
+// BlockedUserValidator denies every request made by a user that a Guarduim
+// currently blocks.
+type BlockedUserValidator struct {
+	Client client.Reader
+}
+
+var _ admission.Handler = &BlockedUserValidator{}
+
+// Handle implements admission.Handler.
+func (v *BlockedUserValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	username := req.UserInfo.Username
+	if username == "" || strings.HasPrefix(username, systemUserPrefix) {
+		return admission.Allowed("")
+	}
+
+	blocked, err := v.Blocked(ctx, username)
+	if err != nil {
+		// Fail open like the webhook failure policy rather than lock out every user
+		blockeduserlog.Error(err, "Failed to look up blocked users", "username", username)
+		return admission.Allowed("")
+	}
+	if blocked {
+		return admission.Denied(fmt.Sprintf("user %q is blocked after repeated failed logins", username))
+	}
+	return admission.Allowed("")
+}
+
+// Blocked reports whether any Guarduim blocks username.
+func (v *BlockedUserValidator) Blocked(ctx context.Context, username string) (bool, error) {
+	guarduims := &guardv1.GuarduimList{}
+	if err := v.Client.List(ctx, guarduims, client.MatchingFields{UsernameField: username}); err != nil {
+		return false, err
+	}
+
+	for _, guarduim := range guarduims.Items {
+		if guarduim.Status.Blocked {
+			return true, nil
+		}
+	}
+	return false, nil
+}
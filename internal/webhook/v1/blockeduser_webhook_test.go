@@ -0,0 +1,81 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	guardv1 "github.com/SaifRehman/guarduim/api/v1"
+)
+
+var _ = Describe("Blocked user Webhook", func() {
+	var validator *BlockedUserValidator
+
+	request := func(username string) admission.Request {
+		return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Create,
+			UserInfo:  authenticationv1.UserInfo{Username: username},
+		}}
+	}
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(guardv1.AddToScheme(scheme)).To(Succeed())
+
+		guarduim := func(name, username string, blocked bool) *guardv1.Guarduim {
+			return &guardv1.Guarduim{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+				Spec:       guardv1.GuarduimSpec{Username: username, Threshold: 5},
+				Status:     guardv1.GuarduimStatus{Blocked: blocked},
+			}
+		}
+		validator = &BlockedUserValidator{Client: fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithIndex(&guardv1.Guarduim{}, UsernameField, IndexUsername).
+			WithObjects(
+				guarduim("alice", "alice", true),
+				guarduim("bob", "bob", false),
+				guarduim("system-admin", "system:admin", true),
+			).
+			Build()}
+	})
+
+	It("Should deny requests from a blocked user", func() {
+		response := validator.Handle(context.Background(), request("alice"))
+		Expect(response.Allowed).To(BeFalse())
+		Expect(response.Result.Message).To(ContainSubstring("alice"))
+	})
+
+	It("Should allow requests from users that are not blocked", func() {
+		for _, username := range []string{"bob", "carol", ""} {
+			Expect(validator.Handle(context.Background(), request(username)).Allowed).To(BeTrue(), username)
+		}
+	})
+
+	It("Should never deny cluster components", func() {
+		Expect(validator.Handle(context.Background(), request("system:admin")).Allowed).To(BeTrue())
+	})
+})
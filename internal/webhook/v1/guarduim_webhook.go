@@ -0,0 +1,226 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	guardv1 "github.com/SaifRehman/guarduim/api/v1"
+	"github.com/SaifRehman/guarduim/internal/rules"
+)
+
+// log is for logging in this package.
+var guarduimlog = logf.Log.WithName("guarduim-resource")
+
+// SetupGuarduimWebhookWithManager registers the webhook for Guarduim in the manager.
+// logSources are the names of the log sources configured on the manager.
+func SetupGuarduimWebhookWithManager(mgr ctrl.Manager, logSources []string) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&guardv1.Guarduim{}).
+		WithValidator(&GuarduimCustomValidator{LogSources: logSources}).
+		WithDefaulter(&GuarduimCustomDefaulter{}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-guard-guarduim-com-v1-guarduim,mutating=true,failurePolicy=fail,sideEffects=None,groups=guard.guarduim.com,resources=guarduims,verbs=create;update,versions=v1,name=mguarduim-v1.kb.io,admissionReviewVersions=v1
+
+// GuarduimCustomDefaulter struct is responsible for setting default values on the custom resource of the
+// Kind Guarduim when those are created or updated.
+type GuarduimCustomDefaulter struct{}
+
+var _ webhook.CustomDefaulter = &GuarduimCustomDefaulter{}
+
+// Default implements webhook.CustomDefaulter so a webhook will be registered for the Kind Guarduim.
+// It records the user requesting a manual unblock, which the controller can not tell otherwise.
+func (d *GuarduimCustomDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	guarduim, ok := obj.(*guardv1.Guarduim)
+	if !ok {
+		return fmt.Errorf("expected a Guarduim object but got %T", obj)
+	}
+
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	var old *guardv1.Guarduim
+	if len(req.OldObject.Raw) > 0 {
+		old = &guardv1.Guarduim{}
+		if err := json.Unmarshal(req.OldObject.Raw, old); err != nil {
+			return err
+		}
+	}
+	setUnblockedBy(guarduim, old, req.UserInfo.Username)
+	return nil
+}
+
+// setUnblockedBy sets UnblockedByAnnotation to username when UnblockAnnotation
+// is set or changed, and removes it when there is no unblock to attribute.
+func setUnblockedBy(guarduim, old *guardv1.Guarduim, username string) {
+	reason, ok := guarduim.Annotations[guardv1.UnblockAnnotation]
+	if !ok {
+		delete(guarduim.Annotations, guardv1.UnblockedByAnnotation)
+		return
+	}
+
+	// Keep the requester of a pending unblock across unrelated updates
+	if old != nil {
+		oldReason, pending := old.Annotations[guardv1.UnblockAnnotation]
+		if by, ok := old.Annotations[guardv1.UnblockedByAnnotation]; pending && ok && oldReason == reason {
+			guarduim.Annotations[guardv1.UnblockedByAnnotation] = by
+			return
+		}
+	}
+	guarduim.Annotations[guardv1.UnblockedByAnnotation] = username
+}
+
+// NOTE: The 'path' attribute must follow a specific pattern and should not be modified directly here.
+// Modifying the path for an invalid path can cause API server errors; failing to locate the webhook.
+// +kubebuilder:webhook:path=/validate-guard-guarduim-com-v1-guarduim,mutating=false,failurePolicy=fail,sideEffects=None,groups=guard.guarduim.com,resources=guarduims,verbs=create;update,versions=v1,name=vguarduim-v1.kb.io,admissionReviewVersions=v1
+
+// GuarduimCustomValidator struct is responsible for validating the Guarduim resource
+// when it is created, updated, or deleted.
+type GuarduimCustomValidator struct {
+	// LogSources are the names spec.logSource may take. Any name is admitted
+	// when empty.
+	LogSources []string
+}
+
+var _ webhook.CustomValidator = &GuarduimCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type Guarduim.
+func (v *GuarduimCustomValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	guarduim, ok := obj.(*guardv1.Guarduim)
+	if !ok {
+		return nil, fmt.Errorf("expected a Guarduim object but got %T", obj)
+	}
+	guarduimlog.Info("Validation for Guarduim upon creation", "name", guarduim.GetName())
+
+	return nil, validateGuarduim(guarduim, v.LogSources)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type Guarduim.
+func (v *GuarduimCustomValidator) ValidateUpdate(_ context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	guarduim, ok := newObj.(*guardv1.Guarduim)
+	if !ok {
+		return nil, fmt.Errorf("expected a Guarduim object for the newObj but got %T", newObj)
+	}
+	old, ok := oldObj.(*guardv1.Guarduim)
+	if !ok {
+		return nil, fmt.Errorf("expected a Guarduim object for the oldObj but got %T", oldObj)
+	}
+	guarduimlog.Info("Validation for Guarduim upon update", "name", guarduim.GetName())
+
+	// The status records the memberships revoked from the user, another
+	// username would be given them back
+	if guarduim.Spec.Username != old.Spec.Username {
+		return nil, apierrors.NewInvalid(guardv1.GroupVersion.WithKind("Guarduim").GroupKind(), guarduim.Name, field.ErrorList{
+			field.Invalid(field.NewPath("spec", "username"), guarduim.Spec.Username, "field is immutable"),
+		})
+	}
+	return nil, validateGuarduim(guarduim, v.LogSources)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type Guarduim.
+func (v *GuarduimCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateGuarduim returns an Invalid error listing every problem with the spec.
+func validateGuarduim(guarduim *guardv1.Guarduim, logSources []string) error {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	usernamePath := specPath.Child("username")
+	for _, msg := range guardv1.ValidateUsername(guarduim.Spec.Username) {
+		allErrs = append(allErrs, field.Invalid(usernamePath, guarduim.Spec.Username, msg))
+	}
+	if guarduim.Spec.Threshold < 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("threshold"), guarduim.Spec.Threshold,
+			"must be greater than or equal to 0"))
+	}
+	allErrs = append(allErrs, validateDurations(specPath, guarduim.Spec.Window,
+		guarduim.Spec.BlockDuration, guarduim.Spec.MaxBlockDuration)...)
+	allErrs = append(allErrs, validateLogSource(specPath.Child("logSource"), guarduim.Spec.LogSource, logSources)...)
+	allErrs = append(allErrs, validateRules(specPath.Child("rules"), guarduim.Spec.Rules)...)
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(guardv1.GroupVersion.WithKind("Guarduim").GroupKind(), guarduim.Name, allErrs)
+}
+
+// validateDurations checks the window and block durations shared by the
+// Guarduim and GuarduimPolicy specs.
+func validateDurations(specPath *field.Path, window, blockDuration, maxBlockDuration *metav1.Duration) field.ErrorList {
+	var allErrs field.ErrorList
+	for _, duration := range []struct {
+		name  string
+		value *metav1.Duration
+	}{
+		{"window", window},
+		{"blockDuration", blockDuration},
+		{"maxBlockDuration", maxBlockDuration},
+	} {
+		if duration.value != nil && duration.value.Duration < 0 {
+			allErrs = append(allErrs, field.Invalid(specPath.Child(duration.name), duration.value.Duration.String(),
+				"must not be negative"))
+		}
+	}
+	if blockDuration != nil && maxBlockDuration != nil &&
+		maxBlockDuration.Duration > 0 && blockDuration.Duration > maxBlockDuration.Duration {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("maxBlockDuration"), maxBlockDuration.Duration.String(),
+			"must not be shorter than blockDuration"))
+	}
+	return allErrs
+}
+
+// validateLogSource checks that name, if set, is one of the logSources
+// configured on the manager, shared by the Guarduim and GuarduimPolicy specs.
+func validateLogSource(path *field.Path, name string, logSources []string) field.ErrorList {
+	if name == "" || len(logSources) == 0 || slices.Contains(logSources, name) {
+		return nil
+	}
+	return field.ErrorList{field.NotSupported(path, name, logSources)}
+}
+
+// validateRules compiles the detection rules shared by the Guarduim and
+// GuarduimPolicy specs and checks that their names are unique.
+func validateRules(path *field.Path, specRules []guardv1.DetectionRule) field.ErrorList {
+	var allErrs field.ErrorList
+	names := map[string]bool{}
+	for i, rule := range specRules {
+		if names[rule.Name] {
+			allErrs = append(allErrs, field.Duplicate(path.Index(i).Child("name"), rule.Name))
+		}
+		names[rule.Name] = true
+		if _, err := rules.Compile(rule.Name, rule.Expression); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Index(i).Child("expression"), rule.Expression, err.Error()))
+		}
+	}
+	return allErrs
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	guardv1 "github.com/SaifRehman/guarduim/api/v1"
+)
+
+var _ = Describe("Guarduim Webhook", func() {
+	var (
+		obj       *guardv1.Guarduim
+		oldObj    *guardv1.Guarduim
+		validator GuarduimCustomValidator
+		defaulter GuarduimCustomDefaulter
+	)
+
+	BeforeEach(func() {
+		obj = &guardv1.Guarduim{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-user", Namespace: "default"},
+			Spec:       guardv1.GuarduimSpec{Username: "alice", Threshold: 5},
+		}
+		oldObj = obj.DeepCopy()
+		validator = GuarduimCustomValidator{}
+		defaulter = GuarduimCustomDefaulter{}
+	})
+
+	Context("When creating or updating Guarduim under Validating Webhook", func() {
+		It("Should admit valid OpenShift and Kubernetes usernames", func() {
+			for _, username := range []string{"alice", "kube:admin", "system:serviceaccount:ci:deployer", "o'brien@example.com"} {
+				obj.Spec.Username = username
+				Expect(validator.ValidateCreate(context.Background(), obj)).Error().NotTo(HaveOccurred(), username)
+			}
+		})
+
+		It("Should deny usernames that could be interpreted by a shell or a path", func() {
+			for _, username := range []string{"", "..", "alice\" ; rm -rf / #", "a/b", "100%", "alice\nbob", "$(id)\t"} {
+				obj.Spec.Username = username
+				Expect(validator.ValidateCreate(context.Background(), obj)).Error().To(HaveOccurred(), username)
+			}
+		})
+
+		It("Should deny a negative threshold on update", func() {
+			obj.Spec.Threshold = -1
+			Expect(validator.ValidateUpdate(context.Background(), oldObj, obj)).Error().To(HaveOccurred())
+		})
+
+		It("Should deny changing the username", func() {
+			obj.Spec.Username = "mallory"
+			Expect(validator.ValidateUpdate(context.Background(), oldObj, obj)).Error().To(
+				MatchError(ContainSubstring("spec.username")))
+		})
+
+		It("Should deny a negative window", func() {
+			obj.Spec.Window = &metav1.Duration{Duration: -time.Minute}
+			Expect(validator.ValidateCreate(context.Background(), obj)).Error().To(HaveOccurred())
+		})
+
+		It("Should deny a block duration longer than the maximum", func() {
+			obj.Spec.BlockDuration = &metav1.Duration{Duration: 2 * time.Hour}
+			obj.Spec.MaxBlockDuration = &metav1.Duration{Duration: time.Hour}
+			Expect(validator.ValidateCreate(context.Background(), obj)).Error().To(HaveOccurred())
+
+			obj.Spec.MaxBlockDuration = &metav1.Duration{}
+			Expect(validator.ValidateCreate(context.Background(), obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny a log source the manager does not read", func() {
+			validator.LogSources = []string{"oauth-server"}
+			obj.Spec.LogSource = "oauth-sever"
+			Expect(validator.ValidateCreate(context.Background(), obj)).Error().To(
+				MatchError(ContainSubstring("spec.logSource")))
+
+			obj.Spec.LogSource = "oauth-server"
+			Expect(validator.ValidateCreate(context.Background(), obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny a detection rule that does not evaluate to a bool", func() {
+			obj.Spec.Rules = []guardv1.DetectionRule{{Name: "count", Expression: "failures"}}
+			Expect(validator.ValidateCreate(context.Background(), obj)).Error().To(HaveOccurred())
+
+			obj.Spec.Rules[0].Expression = "failures > 3"
+			Expect(validator.ValidateCreate(context.Background(), obj)).Error().NotTo(HaveOccurred())
+		})
+	})
+
+	Context("When requesting a manual unblock under Defaulting Webhook", func() {
+		request := func(username string, old *guardv1.Guarduim) context.Context {
+			req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+				UserInfo: authenticationv1.UserInfo{Username: username},
+			}}
+			if old != nil {
+				raw, err := json.Marshal(old)
+				Expect(err).NotTo(HaveOccurred())
+				req.OldObject = runtime.RawExtension{Raw: raw}
+			}
+			return admission.NewContextWithRequest(context.Background(), req)
+		}
+
+		It("Should record the user requesting the unblock", func() {
+			obj.Annotations = map[string]string{
+				guardv1.UnblockAnnotation:     "identity verified",
+				guardv1.UnblockedByAnnotation: "someone-else",
+			}
+			Expect(defaulter.Default(request("helpdesk", oldObj), obj)).To(Succeed())
+			Expect(obj.Annotations).To(HaveKeyWithValue(guardv1.UnblockedByAnnotation, "helpdesk"))
+		})
+
+		It("Should keep the requester of a pending unblock on later updates", func() {
+			oldObj.Annotations = map[string]string{
+				guardv1.UnblockAnnotation:     "identity verified",
+				guardv1.UnblockedByAnnotation: "helpdesk",
+			}
+			obj = oldObj.DeepCopy()
+			obj.Spec.Threshold = 10
+			Expect(defaulter.Default(request("alice", oldObj), obj)).To(Succeed())
+			Expect(obj.Annotations).To(HaveKeyWithValue(guardv1.UnblockedByAnnotation, "helpdesk"))
+		})
+
+		It("Should drop a requester without an unblock", func() {
+			obj.Annotations = map[string]string{guardv1.UnblockedByAnnotation: "helpdesk"}
+			Expect(defaulter.Default(request("alice", nil), obj)).To(Succeed())
+			Expect(obj.Annotations).NotTo(HaveKey(guardv1.UnblockedByAnnotation))
+		})
+	})
+})
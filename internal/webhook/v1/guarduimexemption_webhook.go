@@ -0,0 +1,115 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	guardv1 "github.com/SaifRehman/guarduim/api/v1"
+)
+
+// log is for logging in this package.
+var guarduimexemptionlog = logf.Log.WithName("guarduimexemption-resource")
+
+// SetupGuarduimExemptionWebhookWithManager registers the webhook for GuarduimExemption in the manager.
+func SetupGuarduimExemptionWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&guardv1.GuarduimExemption{}).
+		WithValidator(&GuarduimExemptionCustomValidator{}).
+		Complete()
+}
+
+// NOTE: The 'path' attribute must follow a specific pattern and should not be modified directly here.
+// Modifying the path for an invalid path can cause API server errors; failing to locate the webhook.
+// +kubebuilder:webhook:path=/validate-guard-guarduim-com-v1-guarduimexemption,mutating=false,failurePolicy=fail,sideEffects=None,groups=guard.guarduim.com,resources=guarduimexemptions,verbs=create;update,versions=v1,name=vguarduimexemption-v1.kb.io,admissionReviewVersions=v1
+
+// GuarduimExemptionCustomValidator struct is responsible for validating the GuarduimExemption resource
+// when it is created, updated, or deleted.
+type GuarduimExemptionCustomValidator struct{}
+
+var _ webhook.CustomValidator = &GuarduimExemptionCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type GuarduimExemption.
+func (v *GuarduimExemptionCustomValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	exemption, ok := obj.(*guardv1.GuarduimExemption)
+	if !ok {
+		return nil, fmt.Errorf("expected a GuarduimExemption object but got %T", obj)
+	}
+	guarduimexemptionlog.Info("Validation for GuarduimExemption upon creation", "name", exemption.GetName())
+
+	return nil, validateGuarduimExemption(exemption)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type GuarduimExemption.
+func (v *GuarduimExemptionCustomValidator) ValidateUpdate(_ context.Context, _, newObj runtime.Object) (admission.Warnings, error) {
+	exemption, ok := newObj.(*guardv1.GuarduimExemption)
+	if !ok {
+		return nil, fmt.Errorf("expected a GuarduimExemption object for the newObj but got %T", newObj)
+	}
+	guarduimexemptionlog.Info("Validation for GuarduimExemption upon update", "name", exemption.GetName())
+
+	return nil, validateGuarduimExemption(exemption)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type GuarduimExemption.
+func (v *GuarduimExemptionCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateGuarduimExemption returns an Invalid error listing every problem with the spec.
+func validateGuarduimExemption(exemption *guardv1.GuarduimExemption) error {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	usernamesPath := specPath.Child("usernames")
+	for i, username := range exemption.Spec.Usernames {
+		for _, msg := range guardv1.ValidateUsername(username) {
+			allErrs = append(allErrs, field.Invalid(usernamesPath.Index(i), username, msg))
+		}
+	}
+	patternsPath := specPath.Child("usernamePatterns")
+	for i, pattern := range exemption.Spec.UsernamePatterns {
+		if err := validateUsernamePattern(pattern); err != nil {
+			allErrs = append(allErrs, field.Invalid(patternsPath.Index(i), pattern, err.Error()))
+		}
+	}
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(guardv1.GroupVersion.WithKind("GuarduimExemption").GroupKind(), exemption.Name, allErrs)
+}
+
+// validateUsernamePattern compiles a username pattern the way the controller
+// does, anchored to the whole username. The pattern must compile on its own
+// too, so that it can not close the anchoring group, as "a)|(b" would.
+func validateUsernamePattern(pattern string) error {
+	if _, err := regexp.Compile(pattern); err != nil {
+		return err
+	}
+	_, err := regexp.Compile("^(?:" + pattern + ")$")
+	return err
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	guardv1 "github.com/SaifRehman/guarduim/api/v1"
+)
+
+var _ = Describe("GuarduimExemption Webhook", func() {
+	var (
+		obj       *guardv1.GuarduimExemption
+		oldObj    *guardv1.GuarduimExemption
+		validator GuarduimExemptionCustomValidator
+	)
+
+	BeforeEach(func() {
+		obj = &guardv1.GuarduimExemption{
+			ObjectMeta: metav1.ObjectMeta{Name: "break-glass"},
+			Spec: guardv1.GuarduimExemptionSpec{
+				Usernames:        []string{"kube:admin"},
+				UsernamePatterns: []string{"system:serviceaccount:ci:.*"},
+			},
+		}
+		oldObj = obj.DeepCopy()
+		validator = GuarduimExemptionCustomValidator{}
+	})
+
+	Context("When creating or updating GuarduimExemption under Validating Webhook", func() {
+		It("Should admit a valid exemption", func() {
+			Expect(validator.ValidateCreate(context.Background(), obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny username patterns that do not compile", func() {
+			obj.Spec.UsernamePatterns = []string{"ci-("}
+			Expect(validator.ValidateCreate(context.Background(), obj)).Error().To(HaveOccurred())
+		})
+
+		It("Should deny username patterns escaping the anchors of the controller", func() {
+			obj.Spec.UsernamePatterns = []string{"ci-.*)|(kube:admin"}
+			Expect(validator.ValidateCreate(context.Background(), obj)).Error().To(HaveOccurred())
+		})
+
+		It("Should deny invalid usernames on update", func() {
+			obj.Spec.Usernames = []string{"a/b"}
+			Expect(validator.ValidateUpdate(context.Background(), oldObj, obj)).Error().To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,144 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	guardv1 "github.com/SaifRehman/guarduim/api/v1"
+)
+
+// log is for logging in this package.
+var guarduimpolicylog = logf.Log.WithName("guarduimpolicy-resource")
+
+// SetupGuarduimPolicyWebhookWithManager registers the webhook for GuarduimPolicy in the manager.
+// logSources are the names of the log sources configured on the manager.
+func SetupGuarduimPolicyWebhookWithManager(mgr ctrl.Manager, logSources []string) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&guardv1.GuarduimPolicy{}).
+		WithValidator(&GuarduimPolicyCustomValidator{LogSources: logSources}).
+		Complete()
+}
+
+// NOTE: The 'path' attribute must follow a specific pattern and should not be modified directly here.
+// Modifying the path for an invalid path can cause API server errors; failing to locate the webhook.
+// +kubebuilder:webhook:path=/validate-guard-guarduim-com-v1-guarduimpolicy,mutating=false,failurePolicy=fail,sideEffects=None,groups=guard.guarduim.com,resources=guarduimpolicies,verbs=create;update,versions=v1,name=vguarduimpolicy-v1.kb.io,admissionReviewVersions=v1
+
+// GuarduimPolicyCustomValidator struct is responsible for validating the GuarduimPolicy resource
+// when it is created, updated, or deleted.
+type GuarduimPolicyCustomValidator struct {
+	// LogSources are the names spec.logSource may take. Any name is admitted
+	// when empty.
+	LogSources []string
+}
+
+var _ webhook.CustomValidator = &GuarduimPolicyCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type GuarduimPolicy.
+func (v *GuarduimPolicyCustomValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	policy, ok := obj.(*guardv1.GuarduimPolicy)
+	if !ok {
+		return nil, fmt.Errorf("expected a GuarduimPolicy object but got %T", obj)
+	}
+	guarduimpolicylog.Info("Validation for GuarduimPolicy upon creation", "name", policy.GetName())
+
+	return nil, validateGuarduimPolicy(policy, v.LogSources)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type GuarduimPolicy.
+func (v *GuarduimPolicyCustomValidator) ValidateUpdate(_ context.Context, _, newObj runtime.Object) (admission.Warnings, error) {
+	policy, ok := newObj.(*guardv1.GuarduimPolicy)
+	if !ok {
+		return nil, fmt.Errorf("expected a GuarduimPolicy object for the newObj but got %T", newObj)
+	}
+	guarduimpolicylog.Info("Validation for GuarduimPolicy upon update", "name", policy.GetName())
+
+	return nil, validateGuarduimPolicy(policy, v.LogSources)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type GuarduimPolicy.
+func (v *GuarduimPolicyCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateGuarduimPolicy returns an Invalid error listing every problem with the spec.
+func validateGuarduimPolicy(policy *guardv1.GuarduimPolicy, logSources []string) error {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	patternsPath := specPath.Child("selector", "usernamePatterns")
+	for i, pattern := range policy.Spec.Selector.UsernamePatterns {
+		if err := validateUsernamePattern(pattern); err != nil {
+			allErrs = append(allErrs, field.Invalid(patternsPath.Index(i), pattern, err.Error()))
+		}
+	}
+	if policy.Spec.Threshold < 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("threshold"), policy.Spec.Threshold,
+			"must be greater than or equal to 0"))
+	}
+	allErrs = append(allErrs, validateDurations(specPath, policy.Spec.Window,
+		policy.Spec.BlockDuration, policy.Spec.MaxBlockDuration)...)
+	allErrs = append(allErrs, validateLogSource(specPath.Child("logSource"), policy.Spec.LogSource, logSources)...)
+	allErrs = append(allErrs, validateRules(specPath.Child("rules"), policy.Spec.Rules)...)
+	if blocking := policy.Spec.SourceIPBlocking; blocking != nil {
+		allErrs = append(allErrs, validateSourceIPBlocking(specPath.Child("sourceIPBlocking"), blocking)...)
+	}
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(guardv1.GroupVersion.WithKind("GuarduimPolicy").GroupKind(), policy.Name, allErrs)
+}
+
+// validateSourceIPBlocking checks the thresholds, prefix lengths and pod selector of blocking.
+func validateSourceIPBlocking(path *field.Path, blocking *guardv1.SourceIPBlocking) field.ErrorList {
+	var allErrs field.ErrorList
+	if blocking.Threshold < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("threshold"), blocking.Threshold,
+			"must be greater than or equal to 0"))
+	}
+	for _, prefixLength := range []struct {
+		name     string
+		value    int
+		min, max int
+	}{
+		{"ipv4PrefixLength", blocking.IPv4PrefixLength, 8, 32},
+		{"ipv6PrefixLength", blocking.IPv6PrefixLength, 16, 128},
+	} {
+		// Zero lengths are defaulted
+		if prefixLength.value != 0 && (prefixLength.value < prefixLength.min || prefixLength.value > prefixLength.max) {
+			allErrs = append(allErrs, field.Invalid(path.Child(prefixLength.name), prefixLength.value,
+				fmt.Sprintf("must be between %d and %d", prefixLength.min, prefixLength.max)))
+		}
+	}
+	for _, msg := range validation.IsDNS1123Label(blocking.Namespace) {
+		allErrs = append(allErrs, field.Invalid(path.Child("namespace"), blocking.Namespace, msg))
+	}
+	allErrs = append(allErrs, metav1validation.ValidateLabelSelector(&blocking.PodSelector,
+		metav1validation.LabelSelectorValidationOptions{}, path.Child("podSelector"))...)
+	return allErrs
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	guardv1 "github.com/SaifRehman/guarduim/api/v1"
+)
+
+var _ = Describe("GuarduimPolicy Webhook", func() {
+	var (
+		obj       *guardv1.GuarduimPolicy
+		oldObj    *guardv1.GuarduimPolicy
+		validator GuarduimPolicyCustomValidator
+	)
+
+	BeforeEach(func() {
+		obj = &guardv1.GuarduimPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "developers"},
+			Spec: guardv1.GuarduimPolicySpec{
+				Selector:  guardv1.UserSelector{UsernamePatterns: []string{"dev-.*"}},
+				Threshold: 5,
+			},
+		}
+		oldObj = obj.DeepCopy()
+		validator = GuarduimPolicyCustomValidator{}
+	})
+
+	Context("When creating or updating GuarduimPolicy under Validating Webhook", func() {
+		It("Should admit a valid policy", func() {
+			Expect(validator.ValidateCreate(context.Background(), obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny username patterns that do not compile", func() {
+			obj.Spec.Selector.UsernamePatterns = []string{"dev-("}
+			Expect(validator.ValidateCreate(context.Background(), obj)).Error().To(HaveOccurred())
+		})
+
+		It("Should deny username patterns escaping the anchors of the controller", func() {
+			obj.Spec.Selector.UsernamePatterns = []string{"dev-.*)|(kube:admin"}
+			Expect(validator.ValidateCreate(context.Background(), obj)).Error().To(HaveOccurred())
+		})
+
+		It("Should deny a negative threshold on update", func() {
+			obj.Spec.Threshold = -1
+			Expect(validator.ValidateUpdate(context.Background(), oldObj, obj)).Error().To(HaveOccurred())
+		})
+
+		It("Should admit source IP blocking with defaulted prefix lengths", func() {
+			obj.Spec.SourceIPBlocking = &guardv1.SourceIPBlocking{
+				Threshold:   20,
+				Namespace:   "openshift-ingress",
+				PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "router"}},
+			}
+			Expect(validator.ValidateCreate(context.Background(), obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny source IP blocking with an invalid prefix length or namespace", func() {
+			obj.Spec.SourceIPBlocking = &guardv1.SourceIPBlocking{Threshold: 20, IPv4PrefixLength: 33, Namespace: "Ingress"}
+			err := validateGuarduimPolicy(obj, nil)
+			Expect(err).To(MatchError(ContainSubstring("spec.sourceIPBlocking.ipv4PrefixLength")))
+			Expect(err).To(MatchError(ContainSubstring("spec.sourceIPBlocking.namespace")))
+		})
+
+		It("Should admit detection rules that compile", func() {
+			obj.Spec.Rules = []guardv1.DetectionRule{
+				{Name: "burst", Expression: "failures_10m > 5 && distinct_ips > 2"},
+				{Name: "scripted", Expression: `events.exists(e, e.user_agent.startsWith("python"))`},
+			}
+			Expect(validator.ValidateCreate(context.Background(), obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny detection rules that do not compile or share a name", func() {
+			obj.Spec.Rules = []guardv1.DetectionRule{
+				{Name: "burst", Expression: "failures_10m >"},
+				{Name: "burst", Expression: "failures_2d > 5"},
+				{Name: "count", Expression: "failures + logins"},
+			}
+			err := validateGuarduimPolicy(obj, nil)
+			Expect(err).To(MatchError(ContainSubstring("spec.rules[0].expression")))
+			Expect(err).To(MatchError(ContainSubstring("spec.rules[1].name")))
+			Expect(err).To(MatchError(ContainSubstring("spec.rules[1].expression")))
+			Expect(err).To(MatchError(ContainSubstring("spec.rules[2].expression")))
+		})
+
+		It("Should deny a log source the manager does not read", func() {
+			obj.Spec.LogSource = "oauth-sever"
+			Expect(validateGuarduimPolicy(obj, []string{"oauth-server"})).To(MatchError(ContainSubstring("spec.logSource")))
+		})
+
+		It("Should deny a block duration longer than the maximum", func() {
+			obj.Spec.BlockDuration = &metav1.Duration{Duration: 2 * time.Hour}
+			obj.Spec.MaxBlockDuration = &metav1.Duration{Duration: time.Hour}
+			Expect(validator.ValidateCreate(context.Background(), obj)).Error().To(HaveOccurred())
+		})
+	})
+})